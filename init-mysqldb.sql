@@ -16,14 +16,36 @@ insert into technologies values (
 CREATE TABLE IF NOT EXISTS `targets` (
     `uuid`       CHAR(36) NOT NULL PRIMARY KEY,
     `name`       VARCHAR(255) NOT NULL,
-    `address`    VARCHAR(255) NOT NULL
+    `address`    VARCHAR(255) NOT NULL,
+    `probe`      VARCHAR(16) NOT NULL DEFAULT 'icmp',
+    `port`       INTEGER NOT NULL DEFAULT 0,
+    `query_name` VARCHAR(255) NOT NULL DEFAULT '',
+    `record_type` VARCHAR(10) NOT NULL DEFAULT '',
+    `traceroute` TINYINT(1) NOT NULL DEFAULT 0,
+    `probe_interval` INTEGER NOT NULL DEFAULT 15 COMMENT 'seconds',
+    `probe_timeout`  INTEGER NOT NULL DEFAULT 10000 COMMENT 'milliseconds',
+    `burst_count` INTEGER NOT NULL DEFAULT 1,
+    `burst_spacing` INTEGER NOT NULL DEFAULT 100 COMMENT 'milliseconds',
+    `fail_threshold` INTEGER NOT NULL DEFAULT 3,
+    `success_threshold` INTEGER NOT NULL DEFAULT 2,
+    `degraded_latency` DOUBLE NOT NULL DEFAULT 0,
+    `slow_threshold` INTEGER NOT NULL DEFAULT 3,
+    `fast_threshold` INTEGER NOT NULL DEFAULT 2,
+    `payload_size` INTEGER NOT NULL DEFAULT 0,
+    `dscp` INTEGER NOT NULL DEFAULT 0,
+    `dont_fragment` TINYINT(1) NOT NULL DEFAULT 0,
+    `mtu_discovery` TINYINT(1) NOT NULL DEFAULT 0,
+    `source_address` VARCHAR(45) NOT NULL DEFAULT '',
+    `bind_interface` VARCHAR(15) NOT NULL DEFAULT '',
+    `netns` VARCHAR(64) NOT NULL DEFAULT '',
+    `dual_stack` TINYINT(1) NOT NULL DEFAULT 0
 )
   ENGINE = InnoDB
   DEFAULT CHARSET = utf8
   COLLATE = utf8_general_ci;
 
 INSERT INTO `targets` VALUES (
-  '38c84db2-1c79-40c6-86aa-650474f2cc88', 'localhost', '127.0.0.1'
+  '38c84db2-1c79-40c6-86aa-650474f2cc88', 'localhost', '127.0.0.1', 'icmp', 0, '', '', 0, 15, 10000, 1, 100, 3, 2, 0, 0, 0, 0, 0, '', '', '', 0
 );
 
 CREATE TABLE IF NOT EXISTS `statistics` (
@@ -39,6 +61,30 @@ CREATE TABLE IF NOT EXISTS `statistics` (
     `avg15m`      DOUBLE DEFAULT 0,
     `avg6h`       DOUBLE DEFAULT 0,
     `avg24h`      DOUBLE DEFAULT 0,
+    `jitter`      DOUBLE NOT NULL DEFAULT 0,
+    `burst_min`   DOUBLE NOT NULL DEFAULT 0,
+    `burst_avg`   DOUBLE NOT NULL DEFAULT 0,
+    `burst_max`   DOUBLE NOT NULL DEFAULT 0,
+    `burst_loss`  DOUBLE NOT NULL DEFAULT 0,
+    `rfactor15m`  DOUBLE NOT NULL DEFAULT 0,
+    `rfactor6h`   DOUBLE NOT NULL DEFAULT 0,
+    `rfactor24h`  DOUBLE NOT NULL DEFAULT 0,
+    `mos15m`      DOUBLE NOT NULL DEFAULT 0,
+    `mos6h`       DOUBLE NOT NULL DEFAULT 0,
+    `mos24h`      DOUBLE NOT NULL DEFAULT 0,
+    `loss15m`     DOUBLE NOT NULL DEFAULT 0,
+    `loss6h`      DOUBLE NOT NULL DEFAULT 0,
+    `loss24h`     DOUBLE NOT NULL DEFAULT 0,
+    `late`        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `duplicates`  BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `reordered`   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `corrupted`   BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    `ttl`         INTEGER NOT NULL DEFAULT 0,
+    `hops`        INTEGER NOT NULL DEFAULT 0,
+    `address`     VARCHAR(45) NOT NULL DEFAULT '',
+    `payload_size` INTEGER NOT NULL DEFAULT 0,
+    `dscp`        INTEGER NOT NULL DEFAULT 0,
+    `dont_fragment` TINYINT(1) NOT NULL DEFAULT 0,
     `timestamp`   BIGINT(20) NOT NULL
 )
   ENGINE = InnoDB
@@ -46,8 +92,16 @@ CREATE TABLE IF NOT EXISTS `statistics` (
   COLLATE = utf8_general_ci;
 
 CREATE TABLE IF NOT EXISTS `losses` (
-    `target_uuid` CHAR(36) NOT NULL,
-    `timestamp`   BIGINT(20) NOT NULL,
+    `target_uuid`   CHAR(36) NOT NULL,
+    `timestamp`     BIGINT(20) NOT NULL,
+    `rcode`         VARCHAR(10) NOT NULL DEFAULT '',
+    `reason`        VARCHAR(16) NOT NULL DEFAULT '',
+    `icmp_type`     INTEGER NOT NULL DEFAULT 0,
+    `icmp_code`     INTEGER NOT NULL DEFAULT 0,
+    `payload_size`  INTEGER NOT NULL DEFAULT 0,
+    `dscp`          INTEGER NOT NULL DEFAULT 0,
+    `dont_fragment` TINYINT(1) NOT NULL DEFAULT 0,
+    `address`     VARCHAR(45) NOT NULL DEFAULT '',
     PRIMARY KEY (`target_uuid`, `timestamp`)
 )
   ENGINE = InnoDB
@@ -56,10 +110,17 @@ CREATE TABLE IF NOT EXISTS `losses` (
   COMMENT =  "When a target is unreachable, a record is inserted into this table";
 
 CREATE TABLE IF NOT EXISTS `latencies` (
-    `target_uuid` CHAR(36) NOT NULL,
-    `timestamp`   BIGINT(20) NOT NULL,
-    `latency`     DOUBLE NOT NULL,
-    PRIMARY KEY (`target_uuid`, `timestamp`)
+    `target_uuid`   CHAR(36) NOT NULL,
+    `timestamp`     BIGINT(20) NOT NULL,
+    `latency`       DOUBLE NOT NULL,
+    `rcode`         VARCHAR(10) NOT NULL DEFAULT '',
+    `ttl`           INTEGER NOT NULL DEFAULT 0,
+    `payload_size`  INTEGER NOT NULL DEFAULT 0,
+    `dscp`          INTEGER NOT NULL DEFAULT 0,
+    `dont_fragment` TINYINT(1) NOT NULL DEFAULT 0,
+    `address`     VARCHAR(45) NOT NULL DEFAULT '',
+    PRIMARY KEY (`target_uuid`, `timestamp`),
+    KEY `latencies_timestamp` (`timestamp`)
 )
   ENGINE = InnoDB
   DEFAULT CHARSET = utf8
@@ -75,4 +136,195 @@ CREATE TABLE IF NOT EXISTS `histograms` (
 )
   ENGINE = InnoDB
   DEFAULT CHARSET = utf8
-  COLLATE = utf8_general_ci;
\ No newline at end of file
+  COLLATE = utf8_general_ci;
+
+CREATE TABLE IF NOT EXISTS `http_timings` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `dns`         DOUBLE NOT NULL DEFAULT 0,
+    `connect`     DOUBLE NOT NULL DEFAULT 0,
+    `tls`         DOUBLE NOT NULL DEFAULT 0,
+    `ttfb`        DOUBLE NOT NULL DEFAULT 0,
+    PRIMARY KEY (`target_uuid`, `timestamp`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Duration of the DNS, connect, TLS and TTFB phases of HTTP probes";
+
+CREATE TABLE IF NOT EXISTS `hops` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `hop`         INTEGER NOT NULL,
+    `address`     VARCHAR(45) NOT NULL DEFAULT '',
+    `sent`        INTEGER NOT NULL DEFAULT 0,
+    `recv`        INTEGER NOT NULL DEFAULT 0,
+    `latency`     DOUBLE NOT NULL DEFAULT 0,
+    PRIMARY KEY (`target_uuid`, `timestamp`, `hop`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Hop by hop latency and loss of the path to a target";
+
+CREATE TABLE IF NOT EXISTS `jitters` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `jitter`      DOUBLE NOT NULL,
+    `payload_size` INTEGER NOT NULL DEFAULT 0,
+    `dscp`        INTEGER NOT NULL DEFAULT 0,
+    `dont_fragment` TINYINT(1) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`target_uuid`, `timestamp`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Time Series data of the jitter per target";
+
+CREATE TABLE IF NOT EXISTS `settings` (
+    `setting`     VARCHAR(64) NOT NULL,
+    `value`       VARCHAR(255) NOT NULL,
+    PRIMARY KEY (`setting`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Internal settings of Lagident, e.g. the histogram bucket scheme";
+
+CREATE TABLE IF NOT EXISTS `incidents` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `start_time`  BIGINT(20) NOT NULL,
+    `end_time`    BIGINT(20) NOT NULL DEFAULT 0,
+    `duration`    BIGINT(20) NOT NULL DEFAULT 0,
+    `packets`     INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    PRIMARY KEY (`target_uuid`, `start_time`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Outages per target";
+
+CREATE TABLE IF NOT EXISTS `state_changes` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `state`       VARCHAR(16) NOT NULL,
+    `previous`    VARCHAR(16) NOT NULL DEFAULT '',
+    PRIMARY KEY (`target_uuid`, `timestamp`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "State history per target";
+
+CREATE TABLE IF NOT EXISTS `late_replies` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `seq`         INTEGER NOT NULL,
+    `latency`     DOUBLE NOT NULL,
+    PRIMARY KEY (`target_uuid`, `timestamp`, `seq`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Echo replies that arrived after the timeout";
+
+CREATE TABLE IF NOT EXISTS `packet_events` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `kind`        VARCHAR(16) NOT NULL,
+    `count`       INTEGER UNSIGNED NOT NULL DEFAULT 0,
+    PRIMARY KEY (`target_uuid`, `timestamp`, `kind`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Duplicated, reordered and corrupted echo replies per target";
+
+CREATE TABLE IF NOT EXISTS `path_changes` (
+    `target_uuid`   CHAR(36) NOT NULL,
+    `timestamp`     BIGINT(20) NOT NULL,
+    `previous_hops` INTEGER NOT NULL,
+    `hops`          INTEGER NOT NULL,
+    PRIMARY KEY (`target_uuid`, `timestamp`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Changes of the hop count per target";
+
+CREATE TABLE IF NOT EXISTS `path_mtus` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `mtu`         INTEGER NOT NULL,
+    PRIMARY KEY (`target_uuid`, `timestamp`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Path MTU discovered per target";
+
+CREATE TABLE IF NOT EXISTS `mtu_changes` (
+    `target_uuid`  CHAR(36) NOT NULL,
+    `timestamp`    BIGINT(20) NOT NULL,
+    `previous_mtu` INTEGER NOT NULL,
+    `mtu`          INTEGER NOT NULL,
+    PRIMARY KEY (`target_uuid`, `timestamp`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Changes of the path MTU per target";
+
+CREATE TABLE IF NOT EXISTS `family_results` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `timestamp`   BIGINT(20) NOT NULL,
+    `family`      VARCHAR(4) NOT NULL,
+    `address`     VARCHAR(45) NOT NULL DEFAULT '',
+    `latency`     DOUBLE NOT NULL DEFAULT 0,
+    `lost`        TINYINT(1) NOT NULL DEFAULT 0,
+    `reason`      VARCHAR(16) NOT NULL DEFAULT '',
+    `payload_size` INTEGER NOT NULL DEFAULT 0,
+    `dscp`        INTEGER NOT NULL DEFAULT 0,
+    `dont_fragment` TINYINT(1) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`target_uuid`, `timestamp`, `family`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Results of the IPv4 and IPv6 probes of dual-stack targets";
+
+CREATE TABLE IF NOT EXISTS `family_statistics` (
+    `target_uuid` CHAR(36) NOT NULL,
+    `family`      VARCHAR(4) NOT NULL,
+    `address`     VARCHAR(45) NOT NULL DEFAULT '',
+    `sent`        BIGINT NOT NULL DEFAULT 0,
+    `recv`        BIGINT NOT NULL DEFAULT 0,
+    `last`        DOUBLE NOT NULL DEFAULT 0,
+    `min`         DOUBLE NOT NULL DEFAULT 0,
+    `max`         DOUBLE NOT NULL DEFAULT 0,
+    `avg15m`      DOUBLE NOT NULL DEFAULT 0,
+    `avg6h`       DOUBLE NOT NULL DEFAULT 0,
+    `avg24h`      DOUBLE NOT NULL DEFAULT 0,
+    `loss15m`     DOUBLE NOT NULL DEFAULT 0,
+    `loss6h`      DOUBLE NOT NULL DEFAULT 0,
+    `loss24h`     DOUBLE NOT NULL DEFAULT 0,
+    `timestamp`   BIGINT(20) NOT NULL,
+    PRIMARY KEY (`target_uuid`, `family`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Statistics of the IPv4 and IPv6 probes of dual-stack targets";
+
+CREATE TABLE IF NOT EXISTS `resolution_changes` (
+    `target_uuid`      CHAR(36) NOT NULL,
+    `timestamp`        BIGINT(20) NOT NULL,
+    `family`           VARCHAR(4) NOT NULL DEFAULT '',
+    `previous_address` VARCHAR(45) NOT NULL,
+    `address`          VARCHAR(45) NOT NULL,
+    PRIMARY KEY (`target_uuid`, `timestamp`, `family`)
+)
+  ENGINE = InnoDB
+  DEFAULT CHARSET = utf8
+  COLLATE = utf8_general_ci
+  COMMENT =  "Changes of the resolved address per target";
\ No newline at end of file
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"database/sql"
+	"lagident/model"
+)
+
+// batchStatements are the dialect specific statements of SaveBatch
+type batchStatements struct {
+	stats       string
+	latency     string
+	loss        string
+	jitter      string
+	httpTiming  string
+	measurement string
+
+	stateChange      string
+	incident         string
+	lateReply        string
+	packetEvent      string
+	pathChange       string
+	resolutionChange string
+	familyResult     string
+	familyStats      string
+}
+
+// saveBatch writes all rows of batch in a single transaction. The statistics of
+// targets that were deleted while they waited for the batch are removed again.
+func saveBatch(db *sql.DB, statements batchStatements, batch *model.Batch) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = execEach(tx, statements.stats, len(batch.Stats), func(i int) []any {
+		s := batch.Stats[i]
+		return []any{s.TargetUuid, s.State, s.Sent, s.Recv, s.Last, s.Loss, s.Sum, s.Max, s.Min, s.Avg15m, s.Avg6h, s.Avg24h, s.Jitter, s.BurstMin, s.BurstAvg, s.BurstMax, s.BurstLoss, s.RFactor15m, s.RFactor6h, s.RFactor24h, s.Mos15m, s.Mos6h, s.Mos24h, s.Loss15m, s.Loss6h, s.Loss24h, s.Late, s.Duplicates, s.Reordered, s.Corrupted, s.TTL, s.Hops, s.Address, s.PayloadSize, s.DSCP, s.DontFragment, s.Timestamp}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.latency, len(batch.Latencies), func(i int) []any {
+		l := batch.Latencies[i]
+		return []any{l.TargetUuid, l.Timestamp, l.Latency, l.Rcode, l.TTL, l.PayloadSize, l.DSCP, l.DontFragment, l.Address}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.loss, len(batch.Losses), func(i int) []any {
+		l := batch.Losses[i]
+		return []any{l.TargetUuid, l.Timestamp, l.Rcode, l.Reason, l.ICMPType, l.ICMPCode, l.PayloadSize, l.DSCP, l.DontFragment, l.Address}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.jitter, len(batch.Jitters), func(i int) []any {
+		j := batch.Jitters[i]
+		return []any{j.TargetUuid, j.Timestamp, j.Jitter, j.PayloadSize, j.DSCP, j.DontFragment}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.httpTiming, len(batch.HTTPTimings), func(i int) []any {
+		t := batch.HTTPTimings[i]
+		return []any{t.TargetUuid, t.Timestamp, t.DNS, t.Connect, t.TLS, t.TTFB}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, "DELETE FROM histograms WHERE target_uuid = ?", len(batch.HistogramResets), func(i int) []any {
+		return []any{batch.HistogramResets[i]}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.measurement, len(batch.Measurements), func(i int) []any {
+		m := batch.Measurements[i]
+		return []any{m.TargetUuid, m.Timestamp, m.Bucket}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.stateChange, len(batch.StateChanges), func(i int) []any {
+		c := batch.StateChanges[i]
+		return []any{c.TargetUuid, c.Timestamp, c.State, c.Previous}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.incident, len(batch.Incidents), func(i int) []any {
+		in := batch.Incidents[i]
+		return []any{in.TargetUuid, in.Start, in.End, in.Duration, in.Packets}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.lateReply, len(batch.LateReplies), func(i int) []any {
+		r := batch.LateReplies[i]
+		return []any{r.TargetUuid, r.Timestamp, r.Seq, r.Latency}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.packetEvent, len(batch.PacketEvents), func(i int) []any {
+		e := batch.PacketEvents[i]
+		return []any{e.TargetUuid, e.Timestamp, e.Kind, e.Count}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.pathChange, len(batch.PathChanges), func(i int) []any {
+		c := batch.PathChanges[i]
+		return []any{c.TargetUuid, c.Timestamp, c.PreviousHops, c.Hops}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.resolutionChange, len(batch.ResolutionChanges), func(i int) []any {
+		c := batch.ResolutionChanges[i]
+		return []any{c.TargetUuid, c.Timestamp, c.Family, c.PreviousAddress, c.Address}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.familyResult, len(batch.FamilyResults), func(i int) []any {
+		r := batch.FamilyResults[i]
+		return []any{r.TargetUuid, r.Timestamp, r.Family, r.Address, r.Latency, r.Lost, r.Reason, r.PayloadSize, r.DSCP, r.DontFragment}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = execEach(tx, statements.familyStats, len(batch.FamilyStats), func(i int) []any {
+		s := batch.FamilyStats[i]
+		return []any{s.TargetUuid, s.Family, s.Address, s.Sent, s.Recv, s.Last, s.Min, s.Max, s.Avg15m, s.Avg6h, s.Avg24h, s.Loss15m, s.Loss6h, s.Loss24h, s.Timestamp}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(batch.Stats) > 0 {
+		_, err = tx.Exec("DELETE FROM statistics WHERE target_uuid NOT IN (SELECT uuid FROM targets)")
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(batch.FamilyStats) > 0 {
+		_, err = tx.Exec("DELETE FROM family_statistics WHERE target_uuid NOT IN (SELECT uuid FROM targets)")
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// execEach prepares query in tx and executes it with the arguments of each of the n rows
+func execEach(tx *sql.Tx, query string, n int, args func(i int) []any) error {
+	if n == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		_, err = stmt.Exec(args(i)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
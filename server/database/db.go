@@ -12,19 +12,51 @@ type DB interface {
 	AddTarget(target model.Target) error
 	GetTargetByUuid(uuid string) (*model.Target, error)
 	DeleteTarget(uuid string) error
-	GetStatsByUuid(uuid string) (*model.Stats, error)
 	GetStats() ([]*model.Stats, error)
-	SaveStats(stats model.Stats) error
 	DeleteStats(uuid string) error
-	SaveLoss(loss *model.Loss) error
+	SaveBatch(batch *model.Batch) error
 	DeleteOldLosses(before time.Time) error
 	GetLossByUuid(uuid string) ([]model.Loss, error)
-	SaveLatency(latency *model.Latency) error
 	DeleteOldLatencies(before time.Time) error
 	GetLatencyByUuid(uuid string) ([]model.Latency, error)
-	SaveMeasurement(m *model.HistogramMeasurement) error
+	GetLatencyWindows(since time.Time) (map[string]model.Window, error)
+	GetLatencyByUuidBetween(uuid string, from, to time.Time) ([]model.Latency, error)
 	DeleteOldHistograms(before time.Time) error
 	GetHistogramByUuid(uuid string) ([]*model.HistogramMeasurement, error)
+	RebucketHistograms(scheme model.BucketScheme) error
+	DeleteOldHTTPTimings(before time.Time) error
+	GetHTTPTimingByUuid(uuid string) ([]model.HTTPTiming, error)
+	SaveHops(hops []model.Hop) error
+	DeleteOldHops(before time.Time) error
+	GetHopsByUuid(uuid string) ([]model.Hop, error)
+	DeleteOldJitters(before time.Time) error
+	GetJitterByUuid(uuid string) ([]model.Jitter, error)
+	GetOpenIncidents() ([]model.Incident, error)
+	GetIncidents(uuid string, from, to time.Time) ([]model.Incident, error)
+	DeleteOldIncidents(before time.Time) error
+	GetSetting(setting string) (string, error)
+	SaveSetting(setting string, value string) error
+	DeleteOldStateChanges(before time.Time) error
+	GetStateChangesByUuid(uuid string) ([]model.StateChange, error)
+	DeleteOldLateReplies(before time.Time) error
+	GetLateRepliesByUuid(uuid string) ([]model.LateReply, error)
+	DeleteOldPacketEvents(before time.Time) error
+	GetPacketEventsByUuid(uuid string) ([]model.PacketEvent, error)
+	DeleteOldPathChanges(before time.Time) error
+	GetPathChangesByUuid(uuid string) ([]model.PathChange, error)
+	SavePathMTU(mtu *model.PathMTU) error
+	DeleteOldPathMTUs(before time.Time) error
+	GetPathMTUsByUuid(uuid string) ([]model.PathMTU, error)
+	GetLastPathMTU(uuid string) (*model.PathMTU, error)
+	SaveMTUChange(change *model.MTUChange) error
+	DeleteOldMTUChanges(before time.Time) error
+	GetMTUChangesByUuid(uuid string) ([]model.MTUChange, error)
+	DeleteOldFamilyResults(before time.Time) error
+	GetFamilyResultsByUuid(uuid string) ([]model.FamilyResult, error)
+	DeleteFamilyStats(uuid string) error
+	GetFamilyStats() ([]model.FamilyStats, error)
+	DeleteOldResolutionChanges(before time.Time) error
+	GetResolutionChangesByUuid(uuid string) ([]model.ResolutionChange, error)
 }
 
 func NewDB(db *sql.DB, dbType string) DB {
@@ -60,6 +60,18 @@ func (h *Housekeeping) Start(parent context.Context) {
 				h.db.DeleteOldLatencies(before)
 				h.db.DeleteOldLosses(before)
 				h.db.DeleteOldHistograms(before)
+				h.db.DeleteOldHTTPTimings(before)
+				h.db.DeleteOldHops(before)
+				h.db.DeleteOldJitters(before)
+				h.db.DeleteOldIncidents(before)
+				h.db.DeleteOldStateChanges(before)
+				h.db.DeleteOldLateReplies(before)
+				h.db.DeleteOldPacketEvents(before)
+				h.db.DeleteOldPathChanges(before)
+				h.db.DeleteOldPathMTUs(before)
+				h.db.DeleteOldMTUChanges(before)
+				h.db.DeleteOldFamilyResults(before)
+				h.db.DeleteOldResolutionChanges(before)
 			}
 		}
 
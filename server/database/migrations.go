@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"lagident/model"
+	"log"
+)
+
+// column describes a column that was added to an existing table after the
+// first release, so databases created by older versions need to be altered.
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addedColumns is the list of columns that were added to existing tables.
+// New tables do not belong here, they are created with CREATE TABLE IF NOT EXISTS.
+var addedColumns = []column{
+	{"targets", "probe", "VARCHAR(16) NOT NULL DEFAULT 'icmp'"},
+	{"targets", "port", "INTEGER NOT NULL DEFAULT 0"},
+	{"targets", "query_name", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"targets", "record_type", "VARCHAR(10) NOT NULL DEFAULT ''"},
+	{"targets", "traceroute", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"targets", "probe_interval", "INTEGER NOT NULL DEFAULT 15"},
+	{"targets", "probe_timeout", "INTEGER NOT NULL DEFAULT 10000"},
+	{"targets", "burst_count", "INTEGER NOT NULL DEFAULT 1"},
+	{"targets", "burst_spacing", "INTEGER NOT NULL DEFAULT 100"},
+	{"targets", "fail_threshold", "INTEGER NOT NULL DEFAULT 3"},
+	{"targets", "success_threshold", "INTEGER NOT NULL DEFAULT 2"},
+	{"targets", "degraded_latency", "DOUBLE NOT NULL DEFAULT 0"},
+	{"targets", "slow_threshold", "INTEGER NOT NULL DEFAULT 3"},
+	{"targets", "fast_threshold", "INTEGER NOT NULL DEFAULT 2"},
+	{"targets", "payload_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"targets", "dscp", "INTEGER NOT NULL DEFAULT 0"},
+	{"targets", "dont_fragment", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"targets", "mtu_discovery", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"targets", "source_address", "VARCHAR(45) NOT NULL DEFAULT ''"},
+	{"targets", "bind_interface", "VARCHAR(15) NOT NULL DEFAULT ''"},
+	{"targets", "netns", "VARCHAR(64) NOT NULL DEFAULT ''"},
+	{"targets", "dual_stack", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"latencies", "rcode", "VARCHAR(10) NOT NULL DEFAULT ''"},
+	{"losses", "rcode", "VARCHAR(10) NOT NULL DEFAULT ''"},
+	{"statistics", "jitter", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "burst_min", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "burst_avg", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "burst_max", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "burst_loss", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "rfactor15m", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "rfactor6h", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "rfactor24h", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "mos15m", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "mos6h", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "mos24h", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "loss15m", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "loss6h", "DOUBLE NOT NULL DEFAULT 0"},
+	{"statistics", "loss24h", "DOUBLE NOT NULL DEFAULT 0"},
+	{"losses", "reason", "VARCHAR(16) NOT NULL DEFAULT ''"},
+	{"losses", "icmp_type", "INTEGER NOT NULL DEFAULT 0"},
+	{"losses", "icmp_code", "INTEGER NOT NULL DEFAULT 0"},
+	{"statistics", "late", "BIGINT UNSIGNED NOT NULL DEFAULT 0"},
+	{"statistics", "duplicates", "BIGINT UNSIGNED NOT NULL DEFAULT 0"},
+	{"statistics", "reordered", "BIGINT UNSIGNED NOT NULL DEFAULT 0"},
+	{"statistics", "corrupted", "BIGINT UNSIGNED NOT NULL DEFAULT 0"},
+	{"statistics", "ttl", "INTEGER NOT NULL DEFAULT 0"},
+	{"statistics", "hops", "INTEGER NOT NULL DEFAULT 0"},
+	{"latencies", "ttl", "INTEGER NOT NULL DEFAULT 0"},
+	{"latencies", "payload_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"latencies", "dscp", "INTEGER NOT NULL DEFAULT 0"},
+	{"latencies", "dont_fragment", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"losses", "payload_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"losses", "dscp", "INTEGER NOT NULL DEFAULT 0"},
+	{"losses", "dont_fragment", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"latencies", "address", "VARCHAR(45) NOT NULL DEFAULT ''"},
+	{"statistics", "address", "VARCHAR(45) NOT NULL DEFAULT ''"},
+	{"losses", "address", "VARCHAR(45) NOT NULL DEFAULT ''"},
+	{"statistics", "payload_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"statistics", "dscp", "INTEGER NOT NULL DEFAULT 0"},
+	{"statistics", "dont_fragment", "TINYINT(1) NOT NULL DEFAULT 0"},
+}
+
+// migrateColumns adds all missing columns of addedColumns.
+// hasColumn is dialect specific because SQLite and MySQL expose their schema differently.
+func migrateColumns(db *sql.DB, hasColumn func(table, name string) (bool, error)) error {
+	for _, c := range addedColumns {
+		exists, err := hasColumn(c.table, c.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("error adding column %s.%s: %w", c.table, c.name, err)
+		}
+	}
+
+	return nil
+}
+
+// index describes an index that was added to an existing table after the first release
+type index struct {
+	table   string
+	name    string
+	columns string
+}
+
+// addedIndexes is the list of indexes that were added to existing tables
+var addedIndexes = []index{
+	{"latencies", "latencies_timestamp", "timestamp"},
+}
+
+// migrateIndexes creates all missing indexes of addedIndexes.
+// hasIndex is dialect specific like the hasColumn of migrateColumns.
+func migrateIndexes(db *sql.DB, hasIndex func(table, name string) (bool, error)) error {
+	for _, i := range addedIndexes {
+		exists, err := hasIndex(i.table, i.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("CREATE INDEX %s ON %s (%s)", i.name, i.table, i.columns)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("error creating index %s: %w", i.name, err)
+		}
+	}
+
+	return nil
+}
+
+// settingHistogramScheme stores the bucket scheme the histograms were saved with
+const settingHistogramScheme = "histogram_scheme"
+
+// MigrateHistograms rebuckets the stored histograms if the bucket scheme changed.
+// Databases of older versions have no scheme stored, their buckets are the latency
+// rounded to two digits which get rebucketed like any other latency.
+func MigrateHistograms(db DB, scheme model.BucketScheme) error {
+	current, err := db.GetSetting(settingHistogramScheme)
+	if err != nil {
+		return err
+	}
+	if current == scheme.String() {
+		return nil
+	}
+
+	log.Printf("Rebucket histograms from %q to %q\n", current, scheme.String())
+	err = db.RebucketHistograms(scheme)
+	if err != nil {
+		return fmt.Errorf("error rebucketing histograms: %w", err)
+	}
+
+	return db.SaveSetting(settingHistogramScheme, scheme.String())
+}
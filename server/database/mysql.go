@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"lagident/model"
+	"log"
 	"time"
 )
 
@@ -10,6 +11,40 @@ type MySQLDB struct {
 	db *sql.DB
 }
 
+// Statements of SaveBatch. Results are keyed by the second they were measured in,
+// a second result of a target within the same second is ignored instead of
+// failing the whole batch.
+const (
+	mysqlSaveStats = `
+	INSERT INTO statistics (target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, jitter, burst_min, burst_avg, burst_max, burst_loss, rfactor15m, rfactor6h, rfactor24h, mos15m, mos6h, mos24h, loss15m, loss6h, loss24h, late, duplicates, reordered, corrupted, ttl, hops, address, payload_size, dscp, dont_fragment, timestamp) VALUES (?,?,?,?,?,?,?,?,NULLIF(?, ''),?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+	ON DUPLICATE KEY UPDATE state=VALUES(state), sent=VALUES(sent), recv=VALUES(recv), last=VALUES(last), loss=VALUES(loss), sum=VALUES(sum), max=VALUES(max), min=VALUES(min), avg15m=VALUES(avg15m), avg6h=VALUES(avg6h), avg24h=VALUES(avg24h), jitter=VALUES(jitter), burst_min=VALUES(burst_min), burst_avg=VALUES(burst_avg), burst_max=VALUES(burst_max), burst_loss=VALUES(burst_loss), rfactor15m=VALUES(rfactor15m), rfactor6h=VALUES(rfactor6h), rfactor24h=VALUES(rfactor24h), mos15m=VALUES(mos15m), mos6h=VALUES(mos6h), mos24h=VALUES(mos24h), loss15m=VALUES(loss15m), loss6h=VALUES(loss6h), loss24h=VALUES(loss24h), late=VALUES(late), duplicates=VALUES(duplicates), reordered=VALUES(reordered), corrupted=VALUES(corrupted), ttl=VALUES(ttl), hops=VALUES(hops), address=VALUES(address), payload_size=VALUES(payload_size), dscp=VALUES(dscp), dont_fragment=VALUES(dont_fragment), timestamp=VALUES(timestamp)
+	`
+	mysqlSaveLoss        = "INSERT INTO losses (target_uuid, timestamp, rcode, reason, icmp_type, icmp_code, payload_size, dscp, dont_fragment, address) VALUES (?,?,?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveLatency     = "INSERT INTO latencies (target_uuid, timestamp, latency, rcode, ttl, payload_size, dscp, dont_fragment, address) VALUES (?,?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveMeasurement = `
+	INSERT INTO histograms (target_uuid, timestamp, bucket) VALUES (?,?,?)
+    ON DUPLICATE KEY UPDATE
+    count = count + 1
+	`
+	mysqlSaveHTTPTiming = "INSERT INTO http_timings (target_uuid, timestamp, dns, connect, tls, ttfb) VALUES (?,?,?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveJitter     = "INSERT INTO jitters (target_uuid, timestamp, jitter, payload_size, dscp, dont_fragment) VALUES (?,?,?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+
+	mysqlSaveStateChange = "INSERT INTO state_changes (target_uuid, timestamp, state, previous) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveIncident    = `
+	INSERT INTO incidents (target_uuid, start_time, end_time, duration, packets) VALUES (?,?,?,?,?)
+	ON DUPLICATE KEY UPDATE end_time=VALUES(end_time), duration=VALUES(duration), packets=VALUES(packets)
+	`
+	mysqlSaveLateReply        = "INSERT INTO late_replies (target_uuid, timestamp, seq, latency) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSavePacketEvent      = "INSERT INTO packet_events (target_uuid, timestamp, kind, `count`) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSavePathChange       = "INSERT INTO path_changes (target_uuid, timestamp, previous_hops, hops) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveResolutionChange = "INSERT INTO resolution_changes (target_uuid, timestamp, family, previous_address, address) VALUES (?,?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveFamilyResult     = "INSERT INTO family_results (target_uuid, timestamp, family, address, latency, lost, reason, payload_size, dscp, dont_fragment) VALUES (?,?,?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE target_uuid = target_uuid"
+	mysqlSaveFamilyStats      = `
+	INSERT INTO family_statistics (target_uuid, family, address, sent, recv, last, min, max, avg15m, avg6h, avg24h, loss15m, loss6h, loss24h, timestamp) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+	ON DUPLICATE KEY UPDATE address=VALUES(address), sent=VALUES(sent), recv=VALUES(recv), last=VALUES(last), min=VALUES(min), max=VALUES(max), avg15m=VALUES(avg15m), avg6h=VALUES(avg6h), avg24h=VALUES(avg24h), loss15m=VALUES(loss15m), loss6h=VALUES(loss6h), loss24h=VALUES(loss24h), timestamp=VALUES(timestamp)
+	`
+)
+
 func (d MySQLDB) GetTechnologies() ([]*model.Technology, error) {
 	rows, err := d.db.Query("select name, details from technologies")
 	if err != nil {
@@ -29,7 +64,7 @@ func (d MySQLDB) GetTechnologies() ([]*model.Technology, error) {
 }
 
 func (d MySQLDB) GetTargets() ([]*model.Target, error) {
-	rows, err := d.db.Query("SELECT uuid, name, address from targets")
+	rows, err := d.db.Query("SELECT uuid, name, address, probe, port, query_name, record_type, traceroute, probe_interval, probe_timeout, burst_count, burst_spacing, fail_threshold, success_threshold, degraded_latency, slow_threshold, fast_threshold, payload_size, dscp, dont_fragment, mtu_discovery, source_address, bind_interface, netns, dual_stack FROM targets")
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +72,7 @@ func (d MySQLDB) GetTargets() ([]*model.Target, error) {
 	var targets []*model.Target
 	for rows.Next() {
 		t := new(model.Target)
-		err = rows.Scan(&t.Uuid, &t.Name, &t.Address)
+		err = rows.Scan(&t.Uuid, &t.Name, &t.Address, &t.Probe, &t.Port, &t.QueryName, &t.RecordType, &t.Traceroute, &t.Interval, &t.Timeout, &t.Count, &t.Spacing, &t.FailThreshold, &t.SuccessThreshold, &t.DegradedLatency, &t.SlowThreshold, &t.FastThreshold, &t.PayloadSize, &t.DSCP, &t.DontFragment, &t.MTUDiscovery, &t.SourceAddress, &t.Interface, &t.Netns, &t.DualStack)
 		if err != nil {
 			return nil, err
 		}
@@ -47,13 +82,13 @@ func (d MySQLDB) GetTargets() ([]*model.Target, error) {
 }
 
 func (d MySQLDB) AddTarget(target model.Target) error {
-	stmt, err := d.db.Prepare("INSERT INTO targets (uuid, name, address) VALUES (?, ?, ?)")
+	stmt, err := d.db.Prepare("INSERT INTO targets (uuid, name, address, probe, port, query_name, record_type, traceroute, probe_interval, probe_timeout, burst_count, burst_spacing, fail_threshold, success_threshold, degraded_latency, slow_threshold, fast_threshold, payload_size, dscp, dont_fragment, mtu_discovery, source_address, bind_interface, netns, dual_stack) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(target.Uuid, target.Name, target.Address)
+	_, err = stmt.Exec(target.Uuid, target.Name, target.Address, target.Probe, target.Port, target.QueryName, target.RecordType, target.Traceroute, target.Interval, target.Timeout, target.Count, target.Spacing, target.FailThreshold, target.SuccessThreshold, target.DegradedLatency, target.SlowThreshold, target.FastThreshold, target.PayloadSize, target.DSCP, target.DontFragment, target.MTUDiscovery, target.SourceAddress, target.Interface, target.Netns, target.DualStack)
 	if err != nil {
 		return err
 	}
@@ -63,7 +98,7 @@ func (d MySQLDB) AddTarget(target model.Target) error {
 
 func (d MySQLDB) GetTargetByUuid(uuid string) (*model.Target, error) {
 	var target model.Target
-	err := d.db.QueryRow("SELECT uuid, name, address FROM targets WHERE uuid = ?", uuid).Scan(&target.Uuid, &target.Name, &target.Address)
+	err := d.db.QueryRow("SELECT uuid, name, address, probe, port, query_name, record_type, traceroute, probe_interval, probe_timeout, burst_count, burst_spacing, fail_threshold, success_threshold, degraded_latency, slow_threshold, fast_threshold, payload_size, dscp, dont_fragment, mtu_discovery, source_address, bind_interface, netns, dual_stack FROM targets WHERE uuid = ?", uuid).Scan(&target.Uuid, &target.Name, &target.Address, &target.Probe, &target.Port, &target.QueryName, &target.RecordType, &target.Traceroute, &target.Interval, &target.Timeout, &target.Count, &target.Spacing, &target.FailThreshold, &target.SuccessThreshold, &target.DegradedLatency, &target.SlowThreshold, &target.FastThreshold, &target.PayloadSize, &target.DSCP, &target.DontFragment, &target.MTUDiscovery, &target.SourceAddress, &target.Interface, &target.Netns, &target.DualStack)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No result found
@@ -88,22 +123,8 @@ func (d MySQLDB) DeleteTarget(uuid string) error {
 	return nil
 }
 
-func (d MySQLDB) GetStatsByUuid(uuid string) (*model.Stats, error) {
-	var stats model.Stats
-	err := d.db.QueryRow("SELECT target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, timestamp FROM statistics WHERE target_uuid = ?", uuid).Scan(
-		&stats.TargetUuid, &stats.State, &stats.Sent, &stats.Recv, &stats.Last, &stats.Loss, &stats.Sum, &stats.Max, &stats.Min, &stats.Avg15m, &stats.Avg6h, &stats.Avg24h, &stats.Timestamp,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No result found
-		}
-		return nil, err
-	}
-	return &stats, nil
-}
-
 func (d MySQLDB) GetStats() ([]*model.Stats, error) {
-	rows, err := d.db.Query("SELECT target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, timestamp FROM statistics")
+	rows, err := d.db.Query("SELECT target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, jitter, burst_min, burst_avg, burst_max, burst_loss, rfactor15m, rfactor6h, rfactor24h, mos15m, mos6h, mos24h, loss15m, loss6h, loss24h, late, duplicates, reordered, corrupted, ttl, hops, address, payload_size, dscp, dont_fragment, timestamp FROM statistics")
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +132,7 @@ func (d MySQLDB) GetStats() ([]*model.Stats, error) {
 	var stats []*model.Stats
 	for rows.Next() {
 		s := new(model.Stats)
-		err = rows.Scan(&s.TargetUuid, &s.State, &s.Sent, &s.Recv, &s.Last, &s.Loss, &s.Sum, &s.Max, &s.Min, &s.Avg15m, &s.Avg6h, &s.Avg24h, &s.Timestamp)
+		err = rows.Scan(&s.TargetUuid, &s.State, &s.Sent, &s.Recv, &s.Last, &s.Loss, &s.Sum, &s.Max, &s.Min, &s.Avg15m, &s.Avg6h, &s.Avg24h, &s.Jitter, &s.BurstMin, &s.BurstAvg, &s.BurstMax, &s.BurstLoss, &s.RFactor15m, &s.RFactor6h, &s.RFactor24h, &s.Mos15m, &s.Mos6h, &s.Mos24h, &s.Loss15m, &s.Loss6h, &s.Loss24h, &s.Late, &s.Duplicates, &s.Reordered, &s.Corrupted, &s.TTL, &s.Hops, &s.Address, &s.PayloadSize, &s.DSCP, &s.DontFragment, &s.Timestamp)
 		if err != nil {
 			return nil, err
 		}
@@ -120,20 +141,14 @@ func (d MySQLDB) GetStats() ([]*model.Stats, error) {
 	return stats, nil
 }
 
-func (d MySQLDB) SaveStats(stats model.Stats) error {
-	sql := `
-	INSERT INTO statistics (target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, timestamp) VALUES (?,?,?,?,?,?,?,?,NULLIF(?, ''),?,?,?,?)
-	ON DUPLICATE KEY UPDATE state=VALUES(state), sent=VALUES(sent), recv=VALUES(recv), last=VALUES(last), loss=VALUES(loss), sum=VALUES(sum), max=VALUES(max), min=VALUES(min), avg15m=VALUES(avg15m), avg6h=VALUES(avg6h), avg24h=VALUES(avg24h), timestamp=VALUES(timestamp)
-	`
-	stmt, err := d.db.Prepare(sql)
+func (d MySQLDB) DeleteStats(uuid string) error {
+	stmt, err := d.db.Prepare("DELETE FROM statistics WHERE target_uuid = ?")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		stats.TargetUuid, stats.State, stats.Sent, stats.Recv, stats.Last, stats.Loss, stats.Sum, stats.Max, stats.Min, stats.Avg15m, stats.Avg6h, stats.Avg24h, stats.Timestamp,
-	)
+	_, err = stmt.Exec(uuid)
 	if err != nil {
 		return err
 	}
@@ -141,14 +156,39 @@ func (d MySQLDB) SaveStats(stats model.Stats) error {
 	return nil
 }
 
-func (d MySQLDB) DeleteStats(uuid string) error {
-	stmt, err := d.db.Prepare("DELETE FROM statistics WHERE target_uuid = ?")
+// SaveBatch writes the statistics, results and events of many probes in a single transaction
+func (d MySQLDB) SaveBatch(batch *model.Batch) error {
+	return saveBatch(d.db, batchStatements{
+		stats:       mysqlSaveStats,
+		latency:     mysqlSaveLatency,
+		loss:        mysqlSaveLoss,
+		jitter:      mysqlSaveJitter,
+		httpTiming:  mysqlSaveHTTPTiming,
+		measurement: mysqlSaveMeasurement,
+
+		stateChange:      mysqlSaveStateChange,
+		incident:         mysqlSaveIncident,
+		lateReply:        mysqlSaveLateReply,
+		packetEvent:      mysqlSavePacketEvent,
+		pathChange:       mysqlSavePathChange,
+		resolutionChange: mysqlSaveResolutionChange,
+		familyResult:     mysqlSaveFamilyResult,
+		familyStats:      mysqlSaveFamilyStats,
+	}, batch)
+}
+
+func (d MySQLDB) DeleteOldLosses(before time.Time) error {
+	sql := `
+    DELETE FROM losses
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(uuid)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -156,17 +196,36 @@ func (d MySQLDB) DeleteStats(uuid string) error {
 	return nil
 }
 
-func (d MySQLDB) SaveLoss(loss *model.Loss) error {
-	sql := "INSERT INTO losses (target_uuid, timestamp) VALUES (?,?)"
+func (d MySQLDB) GetLossByUuid(uuid string) ([]model.Loss, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, rcode, reason, icmp_type, icmp_code, payload_size, dscp, dont_fragment, address FROM losses WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Loss
+	for rows.Next() {
+		l := new(model.Loss)
+		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Rcode, &l.Reason, &l.ICMPType, &l.ICMPCode, &l.PayloadSize, &l.DSCP, &l.DontFragment, &l.Address)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *l)
+	}
+	return measurements, nil
+}
+
+func (d MySQLDB) DeleteOldLatencies(before time.Time) error {
+	sql := `
+    DELETE FROM latencies
+    WHERE timestamp < ?
+    `
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		loss.TargetUuid, loss.Timestamp,
-	)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -174,9 +233,94 @@ func (d MySQLDB) SaveLoss(loss *model.Loss) error {
 	return nil
 }
 
-func (d MySQLDB) DeleteOldLosses(before time.Time) error {
+func (d MySQLDB) GetLatencyByUuid(uuid string) ([]model.Latency, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, latency, rcode, ttl, payload_size, dscp, dont_fragment, address FROM latencies WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Latency
+	for rows.Next() {
+		l := new(model.Latency)
+		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Latency, &l.Rcode, &l.TTL, &l.PayloadSize, &l.DSCP, &l.DontFragment, &l.Address)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *l)
+	}
+	return measurements, nil
+}
+
+// mysqlLatencyWindows ranks the latencies since a timestamp per target, leaving out results
+// taken with other probe settings than the current ones. Only the positions that
+// model.NewRankedWindow needs are returned.
+const mysqlLatencyWindows = `SELECT target_uuid, samples, pos, latency FROM (
+        SELECT l.target_uuid, l.latency,
+            ROW_NUMBER() OVER (PARTITION BY l.target_uuid ORDER BY l.latency) - 1 AS pos,
+            COUNT(*) OVER (PARTITION BY l.target_uuid) AS samples
+        FROM latencies l
+        JOIN targets t ON t.uuid = l.target_uuid AND t.payload_size = l.payload_size AND t.dscp = l.dscp AND t.dont_fragment = l.dont_fragment
+        WHERE l.timestamp >= ?
+    ) ranked
+    WHERE pos IN (0, samples - 1,
+        50 * (samples - 1) DIV 100, (50 * (samples - 1) + 99) DIV 100,
+        95 * (samples - 1) DIV 100, (95 * (samples - 1) + 99) DIV 100,
+        99 * (samples - 1) DIV 100, (99 * (samples - 1) + 99) DIV 100)`
+
+func (d MySQLDB) GetLatencyWindows(since time.Time) (map[string]model.Window, error) {
+	rows, err := d.db.Query(mysqlLatencyWindows, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	samples := make(map[string]int)
+	ranked := make(map[string]map[int]float64)
+	for rows.Next() {
+		var uuid string
+		var n, pos int
+		var latency float64
+		err = rows.Scan(&uuid, &n, &pos, &latency)
+		if err != nil {
+			return nil, err
+		}
+		if ranked[uuid] == nil {
+			ranked[uuid] = make(map[int]float64)
+		}
+		samples[uuid] = n
+		ranked[uuid][pos] = latency
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	windows := make(map[string]model.Window, len(samples))
+	for uuid, n := range samples {
+		windows[uuid] = model.NewRankedWindow(n, ranked[uuid])
+	}
+	return windows, nil
+}
+
+func (d MySQLDB) GetLatencyByUuidBetween(uuid string, from, to time.Time) ([]model.Latency, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, latency, rcode, ttl, payload_size, dscp, dont_fragment, address FROM latencies WHERE target_uuid = ? AND timestamp >= ? AND timestamp <= ?  ORDER BY timestamp ASC", uuid, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Latency
+	for rows.Next() {
+		l := new(model.Latency)
+		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Latency, &l.Rcode, &l.TTL, &l.PayloadSize, &l.DSCP, &l.DontFragment, &l.Address)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *l)
+	}
+	return measurements, nil
+}
+
+func (d MySQLDB) DeleteOldHistograms(before time.Time) error {
 	sql := `
-    DELETE FROM losses
+    DELETE FROM histograms
     WHERE timestamp < ?
     `
 	stmt, err := d.db.Prepare(sql)
@@ -193,35 +337,36 @@ func (d MySQLDB) DeleteOldLosses(before time.Time) error {
 	return nil
 }
 
-func (d MySQLDB) GetLossByUuid(uuid string) ([]model.Loss, error) {
-	rows, err := d.db.Query("SELECT target_uuid, timestamp FROM losses WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+func (d MySQLDB) GetHistogramByUuid(uuid string) ([]*model.HistogramMeasurement, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, bucket, `count` FROM histograms WHERE target_uuid = ?  ORDER BY timestamp, bucket ASC", uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var measurements []model.Loss
+	var measurements []*model.HistogramMeasurement
 	for rows.Next() {
-		l := new(model.Loss)
-		err = rows.Scan(&l.TargetUuid, &l.Timestamp)
+		m := new(model.HistogramMeasurement)
+		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.Bucket, &m.Count)
 		if err != nil {
 			return nil, err
 		}
-		measurements = append(measurements, *l)
+		measurements = append(measurements, m)
 	}
 	return measurements, nil
 }
 
-func (d MySQLDB) SaveLatency(latency *model.Latency) error {
-	sql := "INSERT INTO latencies (target_uuid, timestamp, latency) VALUES (?,?,?)"
+func (d MySQLDB) DeleteOldHTTPTimings(before time.Time) error {
+	sql := `
+    DELETE FROM http_timings
+    WHERE timestamp < ?
+    `
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		latency.TargetUuid, latency.Timestamp, latency.Latency,
-	)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -229,9 +374,191 @@ func (d MySQLDB) SaveLatency(latency *model.Latency) error {
 	return nil
 }
 
-func (d MySQLDB) DeleteOldLatencies(before time.Time) error {
+func (d MySQLDB) GetHTTPTimingByUuid(uuid string) ([]model.HTTPTiming, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, dns, connect, tls, ttfb FROM http_timings WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.HTTPTiming
+	for rows.Next() {
+		t := new(model.HTTPTiming)
+		err = rows.Scan(&t.TargetUuid, &t.Timestamp, &t.DNS, &t.Connect, &t.TLS, &t.TTFB)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *t)
+	}
+	return measurements, nil
+}
+
+// mysqlAddedTables contains all tables that were added to init-mysqldb.sql
+// after the first release.
+var mysqlAddedTables = []string{
+	`CREATE TABLE IF NOT EXISTS http_timings (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        dns         DOUBLE NOT NULL DEFAULT 0,
+        connect     DOUBLE NOT NULL DEFAULT 0,
+        tls         DOUBLE NOT NULL DEFAULT 0,
+        ttfb        DOUBLE NOT NULL DEFAULT 0,
+        PRIMARY KEY (target_uuid, timestamp)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS hops (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        hop         INTEGER NOT NULL,
+        address     VARCHAR(45) NOT NULL DEFAULT '',
+        sent        INTEGER NOT NULL DEFAULT 0,
+        recv        INTEGER NOT NULL DEFAULT 0,
+        latency     DOUBLE NOT NULL DEFAULT 0,
+        PRIMARY KEY (target_uuid, timestamp, hop)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS jitters (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        jitter      DOUBLE NOT NULL,
+        payload_size INTEGER NOT NULL DEFAULT 0,
+        dscp        INTEGER NOT NULL DEFAULT 0,
+        dont_fragment TINYINT(1) NOT NULL DEFAULT 0,
+        PRIMARY KEY (target_uuid, timestamp)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS settings (
+        setting     VARCHAR(64) NOT NULL,
+        value       VARCHAR(255) NOT NULL,
+        PRIMARY KEY (setting)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS incidents (
+        target_uuid CHAR(36) NOT NULL,
+        start_time  BIGINT(20) NOT NULL,
+        end_time    BIGINT(20) NOT NULL DEFAULT 0,
+        duration    BIGINT(20) NOT NULL DEFAULT 0,
+        packets     INTEGER UNSIGNED NOT NULL DEFAULT 0,
+        PRIMARY KEY (target_uuid, start_time)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS state_changes (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        state       VARCHAR(16) NOT NULL,
+        previous    VARCHAR(16) NOT NULL DEFAULT '',
+        PRIMARY KEY (target_uuid, timestamp)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS late_replies (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        seq         INTEGER NOT NULL,
+        latency     DOUBLE NOT NULL,
+        PRIMARY KEY (target_uuid, timestamp, seq)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS packet_events (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        kind        VARCHAR(16) NOT NULL,
+        count       INTEGER UNSIGNED NOT NULL DEFAULT 0,
+        PRIMARY KEY (target_uuid, timestamp, kind)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS path_changes (
+        target_uuid   CHAR(36) NOT NULL,
+        timestamp     BIGINT(20) NOT NULL,
+        previous_hops INTEGER NOT NULL,
+        hops          INTEGER NOT NULL,
+        PRIMARY KEY (target_uuid, timestamp)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS path_mtus (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        mtu         INTEGER NOT NULL,
+        PRIMARY KEY (target_uuid, timestamp)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS mtu_changes (
+        target_uuid  CHAR(36) NOT NULL,
+        timestamp    BIGINT(20) NOT NULL,
+        previous_mtu INTEGER NOT NULL,
+        mtu          INTEGER NOT NULL,
+        PRIMARY KEY (target_uuid, timestamp)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS family_results (
+        target_uuid CHAR(36) NOT NULL,
+        timestamp   BIGINT(20) NOT NULL,
+        family      VARCHAR(4) NOT NULL,
+        address     VARCHAR(45) NOT NULL DEFAULT '',
+        latency     DOUBLE NOT NULL DEFAULT 0,
+        lost        TINYINT(1) NOT NULL DEFAULT 0,
+        reason      VARCHAR(16) NOT NULL DEFAULT '',
+        payload_size INTEGER NOT NULL DEFAULT 0,
+        dscp        INTEGER NOT NULL DEFAULT 0,
+        dont_fragment TINYINT(1) NOT NULL DEFAULT 0,
+        PRIMARY KEY (target_uuid, timestamp, family)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS family_statistics (
+        target_uuid CHAR(36) NOT NULL,
+        family      VARCHAR(4) NOT NULL,
+        address     VARCHAR(45) NOT NULL DEFAULT '',
+        sent        BIGINT NOT NULL DEFAULT 0,
+        recv        BIGINT NOT NULL DEFAULT 0,
+        last        DOUBLE NOT NULL DEFAULT 0,
+        min         DOUBLE NOT NULL DEFAULT 0,
+        max         DOUBLE NOT NULL DEFAULT 0,
+        avg15m      DOUBLE NOT NULL DEFAULT 0,
+        avg6h       DOUBLE NOT NULL DEFAULT 0,
+        avg24h      DOUBLE NOT NULL DEFAULT 0,
+        loss15m     DOUBLE NOT NULL DEFAULT 0,
+        loss6h      DOUBLE NOT NULL DEFAULT 0,
+        loss24h     DOUBLE NOT NULL DEFAULT 0,
+        timestamp   BIGINT(20) NOT NULL,
+        PRIMARY KEY (target_uuid, family)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+
+	`CREATE TABLE IF NOT EXISTS resolution_changes (
+        target_uuid      CHAR(36) NOT NULL,
+        timestamp        BIGINT(20) NOT NULL,
+        family           VARCHAR(4) NOT NULL DEFAULT '',
+        previous_address VARCHAR(45) NOT NULL,
+        address          VARCHAR(45) NOT NULL,
+        PRIMARY KEY (target_uuid, timestamp, family)
+    ) ENGINE = InnoDB DEFAULT CHARSET = utf8 COLLATE = utf8_general_ci`,
+}
+
+func (d MySQLDB) SaveHops(hops []model.Hop) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO hops (target_uuid, timestamp, hop, address, sent, recv, latency) VALUES (?,?,?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, h := range hops {
+		_, err = stmt.Exec(
+			h.TargetUuid, h.Timestamp, h.Hop, h.Address, h.Sent, h.Recv, h.Latency,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d MySQLDB) DeleteOldHops(before time.Time) error {
 	sql := `
-    DELETE FROM latencies
+    DELETE FROM hops
     WHERE timestamp < ?
     `
 	stmt, err := d.db.Prepare(sql)
@@ -248,40 +575,36 @@ func (d MySQLDB) DeleteOldLatencies(before time.Time) error {
 	return nil
 }
 
-func (d MySQLDB) GetLatencyByUuid(uuid string) ([]model.Latency, error) {
-	rows, err := d.db.Query("SELECT target_uuid, timestamp, latency FROM latencies WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+func (d MySQLDB) GetHopsByUuid(uuid string) ([]model.Hop, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, hop, address, sent, recv, latency FROM hops WHERE target_uuid = ?  ORDER BY timestamp, hop ASC", uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var measurements []model.Latency
+	var hops []model.Hop
 	for rows.Next() {
-		l := new(model.Latency)
-		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Latency)
+		h := new(model.Hop)
+		err = rows.Scan(&h.TargetUuid, &h.Timestamp, &h.Hop, &h.Address, &h.Sent, &h.Recv, &h.Latency)
 		if err != nil {
 			return nil, err
 		}
-		measurements = append(measurements, *l)
+		hops = append(hops, *h)
 	}
-	return measurements, nil
+	return hops, nil
 }
 
-func (d MySQLDB) SaveMeasurement(m *model.HistogramMeasurement) error {
-	// We do not need count as it is 1 by default
+func (d MySQLDB) DeleteOldJitters(before time.Time) error {
 	sql := `
-	INSERT INTO histograms (target_uuid, timestamp, bucket) VALUES (?,?,?)
-    ON DUPLICATE KEY UPDATE
-    count = count + 1
-	`
+    DELETE FROM jitters
+    WHERE timestamp < ?
+    `
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		m.TargetUuid, m.Timestamp, m.Bucket,
-	)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -289,18 +612,46 @@ func (d MySQLDB) SaveMeasurement(m *model.HistogramMeasurement) error {
 	return nil
 }
 
-func (d MySQLDB) DeleteOldHistograms(before time.Time) error {
+func (d MySQLDB) GetJitterByUuid(uuid string) ([]model.Jitter, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, jitter, payload_size, dscp, dont_fragment FROM jitters WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Jitter
+	for rows.Next() {
+		j := new(model.Jitter)
+		err = rows.Scan(&j.TargetUuid, &j.Timestamp, &j.Jitter, &j.PayloadSize, &j.DSCP, &j.DontFragment)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *j)
+	}
+	return measurements, nil
+}
+
+func (d MySQLDB) GetSetting(setting string) (string, error) {
+	var value string
+	err := d.db.QueryRow("SELECT value FROM settings WHERE setting = ?", setting).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (d MySQLDB) SaveSetting(setting string, value string) error {
 	sql := `
-    DELETE FROM histograms
-    WHERE timestamp < ?
-    `
+	INSERT INTO settings (setting, value) VALUES (?,?)
+	ON DUPLICATE KEY UPDATE
+    value = VALUES(value)
+	`
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(before.Unix())
+	_, err = stmt.Exec(setting, value)
 	if err != nil {
 		return err
 	}
@@ -308,20 +659,534 @@ func (d MySQLDB) DeleteOldHistograms(before time.Time) error {
 	return nil
 }
 
-func (d MySQLDB) GetHistogramByUuid(uuid string) ([]*model.HistogramMeasurement, error) {
-	rows, err := d.db.Query("SELECT target_uuid, timestamp, bucket, `count` FROM histograms WHERE target_uuid = ?  ORDER BY timestamp, bucket ASC", uuid)
+// RebucketHistograms moves all stored histogram counts into the buckets of scheme.
+// Counts that end up in the same bucket are summed up.
+func (d MySQLDB) RebucketHistograms(scheme model.BucketScheme) error {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, bucket, `count` FROM histograms")
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		uuid      string
+		timestamp int64
+		bucket    float64
+	}
+	counts := make(map[key]int64)
+	for rows.Next() {
+		var m model.HistogramMeasurement
+		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.Bucket, &m.Count)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		counts[key{m.TargetUuid, scheme.Timestamp(m.Timestamp), scheme.Bucket(m.Bucket)}] += m.Count
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM histograms")
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO histograms (target_uuid, timestamp, bucket, `count`) VALUES (?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, count := range counts {
+		_, err = stmt.Exec(k.uuid, k.timestamp, k.bucket, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetOpenIncidents returns the ongoing outages of all targets, the latest last
+func (d MySQLDB) GetOpenIncidents() ([]model.Incident, error) {
+	rows, err := d.db.Query("SELECT target_uuid, start_time, end_time, duration, packets FROM incidents WHERE end_time = 0 ORDER BY start_time ASC")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var measurements []*model.HistogramMeasurement
+	var incidents []model.Incident
 	for rows.Next() {
-		m := new(model.HistogramMeasurement)
-		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.Bucket, &m.Count)
+		i := new(model.Incident)
+		err = rows.Scan(&i.TargetUuid, &i.Start, &i.End, &i.Duration, &i.Packets)
 		if err != nil {
 			return nil, err
 		}
-		measurements = append(measurements, m)
+		incidents = append(incidents, *i)
 	}
-	return measurements, nil
+	return incidents, nil
+}
+
+// GetIncidents returns all outages that overlap the time range from - to,
+// of all targets if uuid is empty.
+func (d MySQLDB) GetIncidents(uuid string, from, to time.Time) ([]model.Incident, error) {
+	rows, err := d.db.Query(
+		"SELECT target_uuid, start_time, end_time, duration, packets FROM incidents WHERE (? = '' OR target_uuid = ?) AND start_time <= ? AND (end_time = 0 OR end_time >= ?) ORDER BY start_time ASC",
+		uuid, uuid, to.Unix(), from.Unix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var incidents []model.Incident
+	for rows.Next() {
+		i := new(model.Incident)
+		err = rows.Scan(&i.TargetUuid, &i.Start, &i.End, &i.Duration, &i.Packets)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, *i)
+	}
+	return incidents, nil
+}
+
+func (d MySQLDB) DeleteOldIncidents(before time.Time) error {
+	sql := `
+    DELETE FROM incidents
+    WHERE (end_time <> 0 AND end_time < ?) OR target_uuid NOT IN (SELECT uuid FROM targets)
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) DeleteOldStateChanges(before time.Time) error {
+	sql := `
+    DELETE FROM state_changes
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetStateChangesByUuid(uuid string) ([]model.StateChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, state, previous FROM state_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []model.StateChange
+	for rows.Next() {
+		c := new(model.StateChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.State, &c.Previous)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, nil
+}
+
+func (d MySQLDB) DeleteOldLateReplies(before time.Time) error {
+	sql := `
+    DELETE FROM late_replies
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetLateRepliesByUuid(uuid string) ([]model.LateReply, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, seq, latency FROM late_replies WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var replies []model.LateReply
+	for rows.Next() {
+		r := new(model.LateReply)
+		err = rows.Scan(&r.TargetUuid, &r.Timestamp, &r.Seq, &r.Latency)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, *r)
+	}
+	return replies, nil
+}
+
+func (d MySQLDB) DeleteOldPacketEvents(before time.Time) error {
+	sql := `
+    DELETE FROM packet_events
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetPacketEventsByUuid(uuid string) ([]model.PacketEvent, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, kind, `count` FROM packet_events WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []model.PacketEvent
+	for rows.Next() {
+		e := new(model.PacketEvent)
+		err = rows.Scan(&e.TargetUuid, &e.Timestamp, &e.Kind, &e.Count)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, nil
+}
+
+func (d MySQLDB) DeleteOldPathChanges(before time.Time) error {
+	sql := `
+    DELETE FROM path_changes
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetPathChangesByUuid(uuid string) ([]model.PathChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, previous_hops, hops FROM path_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []model.PathChange
+	for rows.Next() {
+		c := new(model.PathChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.PreviousHops, &c.Hops)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, nil
+}
+
+func (d MySQLDB) SavePathMTU(mtu *model.PathMTU) error {
+	sql := "INSERT INTO path_mtus (target_uuid, timestamp, mtu) VALUES (?,?,?)"
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		mtu.TargetUuid, mtu.Timestamp, mtu.MTU,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) DeleteOldPathMTUs(before time.Time) error {
+	sql := `
+    DELETE FROM path_mtus
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetPathMTUsByUuid(uuid string) ([]model.PathMTU, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, mtu FROM path_mtus WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var mtus []model.PathMTU
+	for rows.Next() {
+		m := new(model.PathMTU)
+		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.MTU)
+		if err != nil {
+			return nil, err
+		}
+		mtus = append(mtus, *m)
+	}
+	return mtus, nil
+}
+
+// GetLastPathMTU returns the latest path MTU of a target or nil if none was discovered yet
+func (d MySQLDB) GetLastPathMTU(uuid string) (*model.PathMTU, error) {
+	m := new(model.PathMTU)
+	err := d.db.QueryRow(
+		"SELECT target_uuid, timestamp, mtu FROM path_mtus WHERE target_uuid = ? ORDER BY timestamp DESC LIMIT 1",
+		uuid,
+	).Scan(&m.TargetUuid, &m.Timestamp, &m.MTU)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (d MySQLDB) SaveMTUChange(change *model.MTUChange) error {
+	sql := "INSERT INTO mtu_changes (target_uuid, timestamp, previous_mtu, mtu) VALUES (?,?,?,?)"
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(
+		change.TargetUuid, change.Timestamp, change.PreviousMTU, change.MTU,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) DeleteOldMTUChanges(before time.Time) error {
+	sql := `
+    DELETE FROM mtu_changes
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetMTUChangesByUuid(uuid string) ([]model.MTUChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, previous_mtu, mtu FROM mtu_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []model.MTUChange
+	for rows.Next() {
+		c := new(model.MTUChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.PreviousMTU, &c.MTU)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, nil
+}
+
+func (d MySQLDB) DeleteOldFamilyResults(before time.Time) error {
+	sql := `
+    DELETE FROM family_results
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetFamilyResultsByUuid(uuid string) ([]model.FamilyResult, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, family, address, latency, lost, reason, payload_size, dscp, dont_fragment FROM family_results WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []model.FamilyResult
+	for rows.Next() {
+		r := new(model.FamilyResult)
+		err = rows.Scan(&r.TargetUuid, &r.Timestamp, &r.Family, &r.Address, &r.Latency, &r.Lost, &r.Reason, &r.PayloadSize, &r.DSCP, &r.DontFragment)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *r)
+	}
+	return results, nil
+}
+
+func (d MySQLDB) DeleteFamilyStats(uuid string) error {
+	stmt, err := d.db.Prepare("DELETE FROM family_statistics WHERE target_uuid = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetFamilyStats returns the statistics of every address family of all dual-stack targets
+func (d MySQLDB) GetFamilyStats() ([]model.FamilyStats, error) {
+	rows, err := d.db.Query("SELECT target_uuid, family, address, sent, recv, last, min, max, avg15m, avg6h, avg24h, loss15m, loss6h, loss24h, timestamp FROM family_statistics ORDER BY target_uuid, family")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []model.FamilyStats
+	for rows.Next() {
+		s := new(model.FamilyStats)
+		err = rows.Scan(&s.TargetUuid, &s.Family, &s.Address, &s.Sent, &s.Recv, &s.Last, &s.Min, &s.Max, &s.Avg15m, &s.Avg6h, &s.Avg24h, &s.Loss15m, &s.Loss6h, &s.Loss24h, &s.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, *s)
+	}
+	return stats, nil
+}
+
+func (d MySQLDB) DeleteOldResolutionChanges(before time.Time) error {
+	sql := `
+    DELETE FROM resolution_changes
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d MySQLDB) GetResolutionChangesByUuid(uuid string) ([]model.ResolutionChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, family, previous_address, address FROM resolution_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []model.ResolutionChange
+	for rows.Next() {
+		c := new(model.ResolutionChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.Family, &c.PreviousAddress, &c.Address)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, nil
+}
+
+// MigrateMySQLDB brings a database that was created by an older version of
+// init-mysqldb.sql up to date.
+func MigrateMySQLDB(db *sql.DB) error {
+	for _, query := range mysqlAddedTables {
+		_, err := db.Exec(query)
+		if err != nil {
+			log.Printf("Error executing query: %s\n", query)
+			return err
+		}
+	}
+
+	err := migrateColumns(db, func(table, name string) (bool, error) {
+		var count int
+		err := db.QueryRow(
+			"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+			table, name,
+		).Scan(&count)
+		return count > 0, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return migrateIndexes(db, func(table, name string) (bool, error) {
+		var count int
+		err := db.QueryRow(
+			"SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+			table, name,
+		).Scan(&count)
+		return count > 0, err
+	})
 }
@@ -11,6 +11,93 @@ type SQLiteDB struct {
 	db *sql.DB
 }
 
+// Statements of SaveBatch. Results are keyed by the second they were measured in,
+// a second result of a target within the same second is ignored instead of
+// failing the whole batch.
+const (
+	sqliteSaveStats = `
+    INSERT INTO statistics (target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, jitter, burst_min, burst_avg, burst_max, burst_loss, rfactor15m, rfactor6h, rfactor24h, mos15m, mos6h, mos24h, loss15m, loss6h, loss24h, late, duplicates, reordered, corrupted, ttl, hops, address, payload_size, dscp, dont_fragment, timestamp)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT(target_uuid) DO UPDATE SET
+        state = excluded.state,
+        sent = excluded.sent,
+        recv = excluded.recv,
+        last = excluded.last,
+        loss = excluded.loss,
+        sum = excluded.sum,
+        max = excluded.max,
+        min = excluded.min,
+        avg15m = excluded.avg15m,
+        avg6h = excluded.avg6h,
+        avg24h = excluded.avg24h,
+        jitter = excluded.jitter,
+        burst_min = excluded.burst_min,
+        burst_avg = excluded.burst_avg,
+        burst_max = excluded.burst_max,
+        burst_loss = excluded.burst_loss,
+        rfactor15m = excluded.rfactor15m,
+        rfactor6h = excluded.rfactor6h,
+        rfactor24h = excluded.rfactor24h,
+        mos15m = excluded.mos15m,
+        mos6h = excluded.mos6h,
+        mos24h = excluded.mos24h,
+        loss15m = excluded.loss15m,
+        loss6h = excluded.loss6h,
+        loss24h = excluded.loss24h,
+        late = excluded.late,
+        duplicates = excluded.duplicates,
+        reordered = excluded.reordered,
+        corrupted = excluded.corrupted,
+        ttl = excluded.ttl,
+        hops = excluded.hops,
+        address = excluded.address,
+        payload_size = excluded.payload_size,
+        dscp = excluded.dscp,
+        dont_fragment = excluded.dont_fragment,
+        timestamp = excluded.timestamp
+`
+	sqliteSaveLoss        = "INSERT OR IGNORE INTO losses (target_uuid, timestamp, rcode, reason, icmp_type, icmp_code, payload_size, dscp, dont_fragment, address) VALUES (?,?,?,?,?,?,?,?,?,?)"
+	sqliteSaveLatency     = "INSERT OR IGNORE INTO latencies (target_uuid, timestamp, latency, rcode, ttl, payload_size, dscp, dont_fragment, address) VALUES (?,?,?,?,?,?,?,?,?)"
+	sqliteSaveMeasurement = `
+	INSERT INTO histograms (target_uuid, timestamp, bucket) VALUES (?,?,?)
+	ON CONFLICT (target_uuid, timestamp, bucket) DO UPDATE
+    SET count = count + 1
+	`
+	sqliteSaveHTTPTiming = "INSERT OR IGNORE INTO http_timings (target_uuid, timestamp, dns, connect, tls, ttfb) VALUES (?,?,?,?,?,?)"
+	sqliteSaveJitter     = "INSERT OR IGNORE INTO jitters (target_uuid, timestamp, jitter, payload_size, dscp, dont_fragment) VALUES (?,?,?,?,?,?)"
+
+	sqliteSaveStateChange = "INSERT OR IGNORE INTO state_changes (target_uuid, timestamp, state, previous) VALUES (?,?,?,?)"
+	sqliteSaveIncident    = `
+	INSERT INTO incidents (target_uuid, start_time, end_time, duration, packets) VALUES (?,?,?,?,?)
+	ON CONFLICT (target_uuid, start_time) DO UPDATE SET
+        end_time = excluded.end_time,
+        duration = excluded.duration,
+        packets = excluded.packets
+	`
+	sqliteSaveLateReply        = "INSERT OR IGNORE INTO late_replies (target_uuid, timestamp, seq, latency) VALUES (?,?,?,?)"
+	sqliteSavePacketEvent      = "INSERT OR IGNORE INTO packet_events (target_uuid, timestamp, kind, `count`) VALUES (?,?,?,?)"
+	sqliteSavePathChange       = "INSERT OR IGNORE INTO path_changes (target_uuid, timestamp, previous_hops, hops) VALUES (?,?,?,?)"
+	sqliteSaveResolutionChange = "INSERT OR IGNORE INTO resolution_changes (target_uuid, timestamp, family, previous_address, address) VALUES (?,?,?,?,?)"
+	sqliteSaveFamilyResult     = "INSERT OR IGNORE INTO family_results (target_uuid, timestamp, family, address, latency, lost, reason, payload_size, dscp, dont_fragment) VALUES (?,?,?,?,?,?,?,?,?,?)"
+	sqliteSaveFamilyStats      = `
+	INSERT INTO family_statistics (target_uuid, family, address, sent, recv, last, min, max, avg15m, avg6h, avg24h, loss15m, loss6h, loss24h, timestamp) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+	ON CONFLICT (target_uuid, family) DO UPDATE SET
+        address = excluded.address,
+        sent = excluded.sent,
+        recv = excluded.recv,
+        last = excluded.last,
+        min = excluded.min,
+        max = excluded.max,
+        avg15m = excluded.avg15m,
+        avg6h = excluded.avg6h,
+        avg24h = excluded.avg24h,
+        loss15m = excluded.loss15m,
+        loss6h = excluded.loss6h,
+        loss24h = excluded.loss24h,
+        timestamp = excluded.timestamp
+	`
+)
+
 func (d SQLiteDB) GetTechnologies() ([]*model.Technology, error) {
 	rows, err := d.db.Query("select name, details from technologies")
 	if err != nil {
@@ -29,32 +116,572 @@ func (d SQLiteDB) GetTechnologies() ([]*model.Technology, error) {
 	return tech, nil
 }
 
-func (d SQLiteDB) GetTargets() ([]*model.Target, error) {
-	rows, err := d.db.Query("SELECT uuid, name, address from targets")
+func (d SQLiteDB) GetTargets() ([]*model.Target, error) {
+	rows, err := d.db.Query("SELECT uuid, name, address, probe, port, query_name, record_type, traceroute, probe_interval, probe_timeout, burst_count, burst_spacing, fail_threshold, success_threshold, degraded_latency, slow_threshold, fast_threshold, payload_size, dscp, dont_fragment, mtu_discovery, source_address, bind_interface, netns, dual_stack FROM targets")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var targets []*model.Target
+	for rows.Next() {
+		t := new(model.Target)
+		err = rows.Scan(&t.Uuid, &t.Name, &t.Address, &t.Probe, &t.Port, &t.QueryName, &t.RecordType, &t.Traceroute, &t.Interval, &t.Timeout, &t.Count, &t.Spacing, &t.FailThreshold, &t.SuccessThreshold, &t.DegradedLatency, &t.SlowThreshold, &t.FastThreshold, &t.PayloadSize, &t.DSCP, &t.DontFragment, &t.MTUDiscovery, &t.SourceAddress, &t.Interface, &t.Netns, &t.DualStack)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+func (d SQLiteDB) AddTarget(target model.Target) error {
+	stmt, err := d.db.Prepare("INSERT INTO targets (uuid, name, address, probe, port, query_name, record_type, traceroute, probe_interval, probe_timeout, burst_count, burst_spacing, fail_threshold, success_threshold, degraded_latency, slow_threshold, fast_threshold, payload_size, dscp, dont_fragment, mtu_discovery, source_address, bind_interface, netns, dual_stack) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(target.Uuid, target.Name, target.Address, target.Probe, target.Port, target.QueryName, target.RecordType, target.Traceroute, target.Interval, target.Timeout, target.Count, target.Spacing, target.FailThreshold, target.SuccessThreshold, target.DegradedLatency, target.SlowThreshold, target.FastThreshold, target.PayloadSize, target.DSCP, target.DontFragment, target.MTUDiscovery, target.SourceAddress, target.Interface, target.Netns, target.DualStack)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetTargetByUuid(uuid string) (*model.Target, error) {
+	var target model.Target
+	err := d.db.QueryRow("SELECT uuid, name, address, probe, port, query_name, record_type, traceroute, probe_interval, probe_timeout, burst_count, burst_spacing, fail_threshold, success_threshold, degraded_latency, slow_threshold, fast_threshold, payload_size, dscp, dont_fragment, mtu_discovery, source_address, bind_interface, netns, dual_stack FROM targets WHERE uuid = ?", uuid).Scan(&target.Uuid, &target.Name, &target.Address, &target.Probe, &target.Port, &target.QueryName, &target.RecordType, &target.Traceroute, &target.Interval, &target.Timeout, &target.Count, &target.Spacing, &target.FailThreshold, &target.SuccessThreshold, &target.DegradedLatency, &target.SlowThreshold, &target.FastThreshold, &target.PayloadSize, &target.DSCP, &target.DontFragment, &target.MTUDiscovery, &target.SourceAddress, &target.Interface, &target.Netns, &target.DualStack)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // No result found
+		}
+		return nil, err
+	}
+	return &target, nil
+}
+
+func (d SQLiteDB) DeleteTarget(uuid string) error {
+	stmt, err := d.db.Prepare("DELETE FROM targets WHERE uuid = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetStats() ([]*model.Stats, error) {
+	rows, err := d.db.Query("SELECT target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, jitter, burst_min, burst_avg, burst_max, burst_loss, rfactor15m, rfactor6h, rfactor24h, mos15m, mos6h, mos24h, loss15m, loss6h, loss24h, late, duplicates, reordered, corrupted, ttl, hops, address, payload_size, dscp, dont_fragment, timestamp FROM statistics")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []*model.Stats
+	for rows.Next() {
+		s := new(model.Stats)
+		err = rows.Scan(&s.TargetUuid, &s.State, &s.Sent, &s.Recv, &s.Last, &s.Loss, &s.Sum, &s.Max, &s.Min, &s.Avg15m, &s.Avg6h, &s.Avg24h, &s.Jitter, &s.BurstMin, &s.BurstAvg, &s.BurstMax, &s.BurstLoss, &s.RFactor15m, &s.RFactor6h, &s.RFactor24h, &s.Mos15m, &s.Mos6h, &s.Mos24h, &s.Loss15m, &s.Loss6h, &s.Loss24h, &s.Late, &s.Duplicates, &s.Reordered, &s.Corrupted, &s.TTL, &s.Hops, &s.Address, &s.PayloadSize, &s.DSCP, &s.DontFragment, &s.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+func (d SQLiteDB) DeleteStats(uuid string) error {
+	stmt, err := d.db.Prepare("DELETE FROM statistics WHERE target_uuid = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveBatch writes the statistics, results and events of many probes in a single transaction
+func (d SQLiteDB) SaveBatch(batch *model.Batch) error {
+	return saveBatch(d.db, batchStatements{
+		stats:       sqliteSaveStats,
+		latency:     sqliteSaveLatency,
+		loss:        sqliteSaveLoss,
+		jitter:      sqliteSaveJitter,
+		httpTiming:  sqliteSaveHTTPTiming,
+		measurement: sqliteSaveMeasurement,
+
+		stateChange:      sqliteSaveStateChange,
+		incident:         sqliteSaveIncident,
+		lateReply:        sqliteSaveLateReply,
+		packetEvent:      sqliteSavePacketEvent,
+		pathChange:       sqliteSavePathChange,
+		resolutionChange: sqliteSaveResolutionChange,
+		familyResult:     sqliteSaveFamilyResult,
+		familyStats:      sqliteSaveFamilyStats,
+	}, batch)
+}
+
+func (d SQLiteDB) DeleteOldLosses(before time.Time) error {
+	sql := `
+    DELETE FROM losses
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetLossByUuid(uuid string) ([]model.Loss, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, rcode, reason, icmp_type, icmp_code, payload_size, dscp, dont_fragment, address FROM losses WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Loss
+	for rows.Next() {
+		l := new(model.Loss)
+		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Rcode, &l.Reason, &l.ICMPType, &l.ICMPCode, &l.PayloadSize, &l.DSCP, &l.DontFragment, &l.Address)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *l)
+	}
+	return measurements, nil
+}
+
+func (d SQLiteDB) DeleteOldLatencies(before time.Time) error {
+	sql := `
+    DELETE FROM latencies
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetLatencyByUuid(uuid string) ([]model.Latency, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, latency, rcode, ttl, payload_size, dscp, dont_fragment, address FROM latencies WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Latency
+	for rows.Next() {
+		l := new(model.Latency)
+		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Latency, &l.Rcode, &l.TTL, &l.PayloadSize, &l.DSCP, &l.DontFragment, &l.Address)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *l)
+	}
+	return measurements, nil
+}
+
+// sqliteLatencyWindows ranks the latencies since a timestamp per target, leaving out results
+// taken with other probe settings than the current ones. Only the positions that
+// model.NewRankedWindow needs are returned.
+const sqliteLatencyWindows = `SELECT target_uuid, samples, pos, latency FROM (
+        SELECT l.target_uuid, l.latency,
+            ROW_NUMBER() OVER (PARTITION BY l.target_uuid ORDER BY l.latency) - 1 AS pos,
+            COUNT(*) OVER (PARTITION BY l.target_uuid) AS samples
+        FROM latencies l
+        JOIN targets t ON t.uuid = l.target_uuid AND t.payload_size = l.payload_size AND t.dscp = l.dscp AND t.dont_fragment = l.dont_fragment
+        WHERE l.timestamp >= ?
+    ) ranked
+    WHERE pos IN (0, samples - 1,
+        50 * (samples - 1) / 100, (50 * (samples - 1) + 99) / 100,
+        95 * (samples - 1) / 100, (95 * (samples - 1) + 99) / 100,
+        99 * (samples - 1) / 100, (99 * (samples - 1) + 99) / 100)`
+
+func (d SQLiteDB) GetLatencyWindows(since time.Time) (map[string]model.Window, error) {
+	rows, err := d.db.Query(sqliteLatencyWindows, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	samples := make(map[string]int)
+	ranked := make(map[string]map[int]float64)
+	for rows.Next() {
+		var uuid string
+		var n, pos int
+		var latency float64
+		err = rows.Scan(&uuid, &n, &pos, &latency)
+		if err != nil {
+			return nil, err
+		}
+		if ranked[uuid] == nil {
+			ranked[uuid] = make(map[int]float64)
+		}
+		samples[uuid] = n
+		ranked[uuid][pos] = latency
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	windows := make(map[string]model.Window, len(samples))
+	for uuid, n := range samples {
+		windows[uuid] = model.NewRankedWindow(n, ranked[uuid])
+	}
+	return windows, nil
+}
+
+func (d SQLiteDB) GetLatencyByUuidBetween(uuid string, from, to time.Time) ([]model.Latency, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, latency, rcode, ttl, payload_size, dscp, dont_fragment, address FROM latencies WHERE target_uuid = ? AND timestamp >= ? AND timestamp <= ?  ORDER BY timestamp ASC", uuid, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Latency
+	for rows.Next() {
+		l := new(model.Latency)
+		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Latency, &l.Rcode, &l.TTL, &l.PayloadSize, &l.DSCP, &l.DontFragment, &l.Address)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *l)
+	}
+	return measurements, nil
+}
+
+func (d SQLiteDB) DeleteOldHistograms(before time.Time) error {
+	sql := `
+    DELETE FROM histograms
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetHistogramByUuid(uuid string) ([]*model.HistogramMeasurement, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, bucket, `count` FROM histograms WHERE target_uuid = ?  ORDER BY timestamp, bucket ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []*model.HistogramMeasurement
+	for rows.Next() {
+		m := new(model.HistogramMeasurement)
+		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.Bucket, &m.Count)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, m)
+	}
+	return measurements, nil
+}
+
+func (d SQLiteDB) DeleteOldHTTPTimings(before time.Time) error {
+	sql := `
+    DELETE FROM http_timings
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetHTTPTimingByUuid(uuid string) ([]model.HTTPTiming, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, dns, connect, tls, ttfb FROM http_timings WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.HTTPTiming
+	for rows.Next() {
+		t := new(model.HTTPTiming)
+		err = rows.Scan(&t.TargetUuid, &t.Timestamp, &t.DNS, &t.Connect, &t.TLS, &t.TTFB)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *t)
+	}
+	return measurements, nil
+}
+
+func (d SQLiteDB) SaveHops(hops []model.Hop) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO hops (target_uuid, timestamp, hop, address, sent, recv, latency) VALUES (?,?,?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, h := range hops {
+		_, err = stmt.Exec(
+			h.TargetUuid, h.Timestamp, h.Hop, h.Address, h.Sent, h.Recv, h.Latency,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d SQLiteDB) DeleteOldHops(before time.Time) error {
+	sql := `
+    DELETE FROM hops
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetHopsByUuid(uuid string) ([]model.Hop, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, hop, address, sent, recv, latency FROM hops WHERE target_uuid = ?  ORDER BY timestamp, hop ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hops []model.Hop
+	for rows.Next() {
+		h := new(model.Hop)
+		err = rows.Scan(&h.TargetUuid, &h.Timestamp, &h.Hop, &h.Address, &h.Sent, &h.Recv, &h.Latency)
+		if err != nil {
+			return nil, err
+		}
+		hops = append(hops, *h)
+	}
+	return hops, nil
+}
+
+func (d SQLiteDB) DeleteOldJitters(before time.Time) error {
+	sql := `
+    DELETE FROM jitters
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetJitterByUuid(uuid string) ([]model.Jitter, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, jitter, payload_size, dscp, dont_fragment FROM jitters WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var measurements []model.Jitter
+	for rows.Next() {
+		j := new(model.Jitter)
+		err = rows.Scan(&j.TargetUuid, &j.Timestamp, &j.Jitter, &j.PayloadSize, &j.DSCP, &j.DontFragment)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *j)
+	}
+	return measurements, nil
+}
+
+func (d SQLiteDB) GetSetting(setting string) (string, error) {
+	var value string
+	err := d.db.QueryRow("SELECT value FROM settings WHERE setting = ?", setting).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (d SQLiteDB) SaveSetting(setting string, value string) error {
+	sql := `
+	INSERT INTO settings (setting, value) VALUES (?,?)
+	ON CONFLICT (setting) DO UPDATE
+    SET value = excluded.value
+	`
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(setting, value)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RebucketHistograms moves all stored histogram counts into the buckets of scheme.
+// Counts that end up in the same bucket are summed up.
+func (d SQLiteDB) RebucketHistograms(scheme model.BucketScheme) error {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, bucket, `count` FROM histograms")
+	if err != nil {
+		return err
+	}
+
+	type key struct {
+		uuid      string
+		timestamp int64
+		bucket    float64
+	}
+	counts := make(map[key]int64)
+	for rows.Next() {
+		var m model.HistogramMeasurement
+		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.Bucket, &m.Count)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		counts[key{m.TargetUuid, scheme.Timestamp(m.Timestamp), scheme.Bucket(m.Bucket)}] += m.Count
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM histograms")
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO histograms (target_uuid, timestamp, bucket, `count`) VALUES (?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, count := range counts {
+		_, err = stmt.Exec(k.uuid, k.timestamp, k.bucket, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetOpenIncidents returns the ongoing outages of all targets, the latest last
+func (d SQLiteDB) GetOpenIncidents() ([]model.Incident, error) {
+	rows, err := d.db.Query("SELECT target_uuid, start_time, end_time, duration, packets FROM incidents WHERE end_time = 0 ORDER BY start_time ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var incidents []model.Incident
+	for rows.Next() {
+		i := new(model.Incident)
+		err = rows.Scan(&i.TargetUuid, &i.Start, &i.End, &i.Duration, &i.Packets)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, *i)
+	}
+	return incidents, nil
+}
+
+// GetIncidents returns all outages that overlap the time range from - to,
+// of all targets if uuid is empty.
+func (d SQLiteDB) GetIncidents(uuid string, from, to time.Time) ([]model.Incident, error) {
+	rows, err := d.db.Query(
+		"SELECT target_uuid, start_time, end_time, duration, packets FROM incidents WHERE (? = '' OR target_uuid = ?) AND start_time <= ? AND (end_time = 0 OR end_time >= ?) ORDER BY start_time ASC",
+		uuid, uuid, to.Unix(), from.Unix(),
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var targets []*model.Target
+	var incidents []model.Incident
 	for rows.Next() {
-		t := new(model.Target)
-		err = rows.Scan(&t.Uuid, &t.Name, &t.Address)
+		i := new(model.Incident)
+		err = rows.Scan(&i.TargetUuid, &i.Start, &i.End, &i.Duration, &i.Packets)
 		if err != nil {
 			return nil, err
 		}
-		targets = append(targets, t)
+		incidents = append(incidents, *i)
 	}
-	return targets, nil
+	return incidents, nil
 }
 
-func (d SQLiteDB) AddTarget(target model.Target) error {
-	stmt, err := d.db.Prepare("INSERT INTO targets (uuid, name, address) VALUES (?, ?, ?)")
+func (d SQLiteDB) DeleteOldIncidents(before time.Time) error {
+	sql := `
+    DELETE FROM incidents
+    WHERE (end_time <> 0 AND end_time < ?) OR target_uuid NOT IN (SELECT uuid FROM targets)
+    `
+	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(target.Uuid, target.Name, target.Address)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -62,26 +689,18 @@ func (d SQLiteDB) AddTarget(target model.Target) error {
 	return nil
 }
 
-func (d SQLiteDB) GetTargetByUuid(uuid string) (*model.Target, error) {
-	var target model.Target
-	err := d.db.QueryRow("SELECT uuid, name, address FROM targets WHERE uuid = ?", uuid).Scan(&target.Uuid, &target.Name, &target.Address)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No result found
-		}
-		return nil, err
-	}
-	return &target, nil
-}
-
-func (d SQLiteDB) DeleteTarget(uuid string) error {
-	stmt, err := d.db.Prepare("DELETE FROM targets WHERE uuid = ?")
+func (d SQLiteDB) DeleteOldStateChanges(before time.Time) error {
+	sql := `
+    DELETE FROM state_changes
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(uuid)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -89,65 +708,73 @@ func (d SQLiteDB) DeleteTarget(uuid string) error {
 	return nil
 }
 
-func (d SQLiteDB) GetStatsByUuid(uuid string) (*model.Stats, error) {
-	var stats model.Stats
-	err := d.db.QueryRow("SELECT target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, timestamp FROM statistics WHERE target_uuid = ?", uuid).Scan(
-		&stats.TargetUuid, &stats.State, &stats.Sent, &stats.Recv, &stats.Last, &stats.Loss, &stats.Sum, &stats.Max, &stats.Min, &stats.Avg15m, &stats.Avg6h, &stats.Avg24h, &stats.Timestamp,
-	)
+func (d SQLiteDB) GetStateChangesByUuid(uuid string) ([]model.StateChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, state, previous FROM state_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // No result found
-		}
 		return nil, err
 	}
-	return &stats, nil
+	defer rows.Close()
+	var changes []model.StateChange
+	for rows.Next() {
+		c := new(model.StateChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.State, &c.Previous)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, nil
 }
 
-func (d SQLiteDB) GetStats() ([]*model.Stats, error) {
-	rows, err := d.db.Query("SELECT target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, timestamp FROM statistics")
+func (d SQLiteDB) DeleteOldLateReplies(before time.Time) error {
+	sql := `
+    DELETE FROM late_replies
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before.Unix())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d SQLiteDB) GetLateRepliesByUuid(uuid string) ([]model.LateReply, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, seq, latency FROM late_replies WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var stats []*model.Stats
+	var replies []model.LateReply
 	for rows.Next() {
-		s := new(model.Stats)
-		err = rows.Scan(&s.TargetUuid, &s.State, &s.Sent, &s.Recv, &s.Last, &s.Loss, &s.Sum, &s.Max, &s.Min, &s.Avg15m, &s.Avg6h, &s.Avg24h, &s.Timestamp)
+		r := new(model.LateReply)
+		err = rows.Scan(&r.TargetUuid, &r.Timestamp, &r.Seq, &r.Latency)
 		if err != nil {
 			return nil, err
 		}
-		stats = append(stats, s)
+		replies = append(replies, *r)
 	}
-	return stats, nil
+	return replies, nil
 }
 
-func (d SQLiteDB) SaveStats(stats model.Stats) error {
+func (d SQLiteDB) DeleteOldPacketEvents(before time.Time) error {
 	sql := `
-    INSERT INTO statistics (target_uuid, state, sent, recv, last, loss, sum, max, min, avg15m, avg6h, avg24h, timestamp)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
-    ON CONFLICT(target_uuid) DO UPDATE SET
-        state = excluded.state,
-        sent = excluded.sent,
-        recv = excluded.recv,
-        last = excluded.last,
-        loss = excluded.loss,
-        sum = excluded.sum,
-        max = excluded.max,
-        min = excluded.min,
-        avg15m = excluded.avg15m,
-        avg6h = excluded.avg6h,
-        avg24h = excluded.avg24h,
-        timestamp = excluded.timestamp
-`
+    DELETE FROM packet_events
+    WHERE timestamp < ?
+    `
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		stats.TargetUuid, stats.State, stats.Sent, stats.Recv, stats.Last, stats.Loss, stats.Sum, stats.Max, stats.Min, stats.Avg15m, stats.Avg6h, stats.Avg24h, stats.Timestamp,
-	)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -155,14 +782,36 @@ func (d SQLiteDB) SaveStats(stats model.Stats) error {
 	return nil
 }
 
-func (d SQLiteDB) DeleteStats(uuid string) error {
-	stmt, err := d.db.Prepare("DELETE FROM statistics WHERE target_uuid = ?")
+func (d SQLiteDB) GetPacketEventsByUuid(uuid string) ([]model.PacketEvent, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, kind, `count` FROM packet_events WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []model.PacketEvent
+	for rows.Next() {
+		e := new(model.PacketEvent)
+		err = rows.Scan(&e.TargetUuid, &e.Timestamp, &e.Kind, &e.Count)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, nil
+}
+
+func (d SQLiteDB) DeleteOldPathChanges(before time.Time) error {
+	sql := `
+    DELETE FROM path_changes
+    WHERE timestamp < ?
+    `
+	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(uuid)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -170,8 +819,26 @@ func (d SQLiteDB) DeleteStats(uuid string) error {
 	return nil
 }
 
-func (d SQLiteDB) SaveLoss(loss *model.Loss) error {
-	sql := "INSERT INTO losses (target_uuid, timestamp) VALUES (?,?)"
+func (d SQLiteDB) GetPathChangesByUuid(uuid string) ([]model.PathChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, previous_hops, hops FROM path_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []model.PathChange
+	for rows.Next() {
+		c := new(model.PathChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.PreviousHops, &c.Hops)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *c)
+	}
+	return changes, nil
+}
+
+func (d SQLiteDB) SavePathMTU(mtu *model.PathMTU) error {
+	sql := "INSERT INTO path_mtus (target_uuid, timestamp, mtu) VALUES (?,?,?)"
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
@@ -179,7 +846,7 @@ func (d SQLiteDB) SaveLoss(loss *model.Loss) error {
 	defer stmt.Close()
 
 	_, err = stmt.Exec(
-		loss.TargetUuid, loss.Timestamp,
+		mtu.TargetUuid, mtu.Timestamp, mtu.MTU,
 	)
 	if err != nil {
 		return err
@@ -188,9 +855,9 @@ func (d SQLiteDB) SaveLoss(loss *model.Loss) error {
 	return nil
 }
 
-func (d SQLiteDB) DeleteOldLosses(before time.Time) error {
+func (d SQLiteDB) DeleteOldPathMTUs(before time.Time) error {
 	sql := `
-    DELETE FROM losses
+    DELETE FROM path_mtus
     WHERE timestamp < ?
     `
 	stmt, err := d.db.Prepare(sql)
@@ -207,26 +874,42 @@ func (d SQLiteDB) DeleteOldLosses(before time.Time) error {
 	return nil
 }
 
-func (d SQLiteDB) GetLossByUuid(uuid string) ([]model.Loss, error) {
-	rows, err := d.db.Query("SELECT target_uuid, timestamp FROM losses WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+func (d SQLiteDB) GetPathMTUsByUuid(uuid string) ([]model.PathMTU, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, mtu FROM path_mtus WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var measurements []model.Loss
+	var mtus []model.PathMTU
 	for rows.Next() {
-		l := new(model.Loss)
-		err = rows.Scan(&l.TargetUuid, &l.Timestamp)
+		m := new(model.PathMTU)
+		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.MTU)
 		if err != nil {
 			return nil, err
 		}
-		measurements = append(measurements, *l)
+		mtus = append(mtus, *m)
 	}
-	return measurements, nil
+	return mtus, nil
+}
+
+// GetLastPathMTU returns the latest path MTU of a target or nil if none was discovered yet
+func (d SQLiteDB) GetLastPathMTU(uuid string) (*model.PathMTU, error) {
+	m := new(model.PathMTU)
+	err := d.db.QueryRow(
+		"SELECT target_uuid, timestamp, mtu FROM path_mtus WHERE target_uuid = ? ORDER BY timestamp DESC LIMIT 1",
+		uuid,
+	).Scan(&m.TargetUuid, &m.Timestamp, &m.MTU)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (d SQLiteDB) SaveLatency(latency *model.Latency) error {
-	sql := "INSERT INTO latencies (target_uuid, timestamp, latency) VALUES (?,?,?)"
+func (d SQLiteDB) SaveMTUChange(change *model.MTUChange) error {
+	sql := "INSERT INTO mtu_changes (target_uuid, timestamp, previous_mtu, mtu) VALUES (?,?,?,?)"
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
@@ -234,7 +917,7 @@ func (d SQLiteDB) SaveLatency(latency *model.Latency) error {
 	defer stmt.Close()
 
 	_, err = stmt.Exec(
-		latency.TargetUuid, latency.Timestamp, latency.Latency,
+		change.TargetUuid, change.Timestamp, change.PreviousMTU, change.MTU,
 	)
 	if err != nil {
 		return err
@@ -243,9 +926,9 @@ func (d SQLiteDB) SaveLatency(latency *model.Latency) error {
 	return nil
 }
 
-func (d SQLiteDB) DeleteOldLatencies(before time.Time) error {
+func (d SQLiteDB) DeleteOldMTUChanges(before time.Time) error {
 	sql := `
-    DELETE FROM latencies
+    DELETE FROM mtu_changes
     WHERE timestamp < ?
     `
 	stmt, err := d.db.Prepare(sql)
@@ -262,40 +945,36 @@ func (d SQLiteDB) DeleteOldLatencies(before time.Time) error {
 	return nil
 }
 
-func (d SQLiteDB) GetLatencyByUuid(uuid string) ([]model.Latency, error) {
-	rows, err := d.db.Query("SELECT target_uuid, timestamp, latency FROM latencies WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+func (d SQLiteDB) GetMTUChangesByUuid(uuid string) ([]model.MTUChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, previous_mtu, mtu FROM mtu_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var measurements []model.Latency
+	var changes []model.MTUChange
 	for rows.Next() {
-		l := new(model.Latency)
-		err = rows.Scan(&l.TargetUuid, &l.Timestamp, &l.Latency)
+		c := new(model.MTUChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.PreviousMTU, &c.MTU)
 		if err != nil {
 			return nil, err
 		}
-		measurements = append(measurements, *l)
+		changes = append(changes, *c)
 	}
-	return measurements, nil
+	return changes, nil
 }
 
-func (d SQLiteDB) SaveMeasurement(m *model.HistogramMeasurement) error {
-	// We do not need count as it is 1 by default
+func (d SQLiteDB) DeleteOldFamilyResults(before time.Time) error {
 	sql := `
-	INSERT INTO histograms (target_uuid, timestamp, bucket) VALUES (?,?,?)
-	ON CONFLICT (target_uuid, timestamp, bucket) DO UPDATE
-    SET count = count + 1
-	`
+    DELETE FROM family_results
+    WHERE timestamp < ?
+    `
 	stmt, err := d.db.Prepare(sql)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(
-		m.TargetUuid, m.Timestamp, m.Bucket,
-	)
+	_, err = stmt.Exec(before.Unix())
 	if err != nil {
 		return err
 	}
@@ -303,9 +982,62 @@ func (d SQLiteDB) SaveMeasurement(m *model.HistogramMeasurement) error {
 	return nil
 }
 
-func (d SQLiteDB) DeleteOldHistograms(before time.Time) error {
+func (d SQLiteDB) GetFamilyResultsByUuid(uuid string) ([]model.FamilyResult, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, family, address, latency, lost, reason, payload_size, dscp, dont_fragment FROM family_results WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []model.FamilyResult
+	for rows.Next() {
+		r := new(model.FamilyResult)
+		err = rows.Scan(&r.TargetUuid, &r.Timestamp, &r.Family, &r.Address, &r.Latency, &r.Lost, &r.Reason, &r.PayloadSize, &r.DSCP, &r.DontFragment)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *r)
+	}
+	return results, nil
+}
+
+func (d SQLiteDB) DeleteFamilyStats(uuid string) error {
+	stmt, err := d.db.Prepare("DELETE FROM family_statistics WHERE target_uuid = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetFamilyStats returns the statistics of every address family of all dual-stack targets
+func (d SQLiteDB) GetFamilyStats() ([]model.FamilyStats, error) {
+	rows, err := d.db.Query("SELECT target_uuid, family, address, sent, recv, last, min, max, avg15m, avg6h, avg24h, loss15m, loss6h, loss24h, timestamp FROM family_statistics ORDER BY target_uuid, family")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []model.FamilyStats
+	for rows.Next() {
+		s := new(model.FamilyStats)
+		err = rows.Scan(&s.TargetUuid, &s.Family, &s.Address, &s.Sent, &s.Recv, &s.Last, &s.Min, &s.Max, &s.Avg15m, &s.Avg6h, &s.Avg24h, &s.Loss15m, &s.Loss6h, &s.Loss24h, &s.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, *s)
+	}
+	return stats, nil
+}
+
+func (d SQLiteDB) DeleteOldResolutionChanges(before time.Time) error {
 	sql := `
-    DELETE FROM histograms
+    DELETE FROM resolution_changes
     WHERE timestamp < ?
     `
 	stmt, err := d.db.Prepare(sql)
@@ -322,22 +1054,22 @@ func (d SQLiteDB) DeleteOldHistograms(before time.Time) error {
 	return nil
 }
 
-func (d SQLiteDB) GetHistogramByUuid(uuid string) ([]*model.HistogramMeasurement, error) {
-	rows, err := d.db.Query("SELECT target_uuid, timestamp, bucket, `count` FROM histograms WHERE target_uuid = ?  ORDER BY timestamp, bucket ASC", uuid)
+func (d SQLiteDB) GetResolutionChangesByUuid(uuid string) ([]model.ResolutionChange, error) {
+	rows, err := d.db.Query("SELECT target_uuid, timestamp, family, previous_address, address FROM resolution_changes WHERE target_uuid = ?  ORDER BY timestamp ASC", uuid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var measurements []*model.HistogramMeasurement
+	var changes []model.ResolutionChange
 	for rows.Next() {
-		m := new(model.HistogramMeasurement)
-		err = rows.Scan(&m.TargetUuid, &m.Timestamp, &m.Bucket, &m.Count)
+		c := new(model.ResolutionChange)
+		err = rows.Scan(&c.TargetUuid, &c.Timestamp, &c.Family, &c.PreviousAddress, &c.Address)
 		if err != nil {
 			return nil, err
 		}
-		measurements = append(measurements, m)
+		changes = append(changes, *c)
 	}
-	return measurements, nil
+	return changes, nil
 }
 
 func InitializeSQLiteDB(db *sql.DB) error {
@@ -345,10 +1077,32 @@ func InitializeSQLiteDB(db *sql.DB) error {
 		`CREATE TABLE IF NOT EXISTS targets (
             uuid CHAR(36) NOT NULL PRIMARY KEY,
             name TEXT NOT NULL,
-            address TEXT NOT NULL
+            address TEXT NOT NULL,
+            probe TEXT NOT NULL DEFAULT 'icmp',
+            port INTEGER NOT NULL DEFAULT 0,
+            query_name TEXT NOT NULL DEFAULT '',
+            record_type TEXT NOT NULL DEFAULT '',
+            traceroute INTEGER NOT NULL DEFAULT 0,
+            probe_interval INTEGER NOT NULL DEFAULT 15,
+            probe_timeout INTEGER NOT NULL DEFAULT 10000,
+            burst_count INTEGER NOT NULL DEFAULT 1,
+            burst_spacing INTEGER NOT NULL DEFAULT 100,
+            fail_threshold INTEGER NOT NULL DEFAULT 3,
+            success_threshold INTEGER NOT NULL DEFAULT 2,
+            degraded_latency REAL NOT NULL DEFAULT 0,
+            slow_threshold INTEGER NOT NULL DEFAULT 3,
+            fast_threshold INTEGER NOT NULL DEFAULT 2,
+            payload_size INTEGER NOT NULL DEFAULT 0,
+            dscp INTEGER NOT NULL DEFAULT 0,
+            dont_fragment INTEGER NOT NULL DEFAULT 0,
+            mtu_discovery INTEGER NOT NULL DEFAULT 0,
+            source_address TEXT NOT NULL DEFAULT '',
+            bind_interface TEXT NOT NULL DEFAULT '',
+            netns TEXT NOT NULL DEFAULT '',
+            dual_stack INTEGER NOT NULL DEFAULT 0
         );`,
 
-		`INSERT OR IGNORE INTO targets VALUES (
+		`INSERT OR IGNORE INTO targets (uuid, name, address) VALUES (
             '38c84db2-1c79-40c6-86aa-650474f2cc88', 'localhost', '127.0.0.1'
         );`,
 
@@ -365,12 +1119,44 @@ func InitializeSQLiteDB(db *sql.DB) error {
             avg15m REAL DEFAULT 0,
             avg6h REAL DEFAULT 0,
             avg24h REAL DEFAULT 0,
+            jitter REAL DEFAULT 0,
+            burst_min REAL DEFAULT 0,
+            burst_avg REAL DEFAULT 0,
+            burst_max REAL DEFAULT 0,
+            burst_loss REAL DEFAULT 0,
+            rfactor15m REAL DEFAULT 0,
+            rfactor6h REAL DEFAULT 0,
+            rfactor24h REAL DEFAULT 0,
+            mos15m REAL DEFAULT 0,
+            mos6h REAL DEFAULT 0,
+            mos24h REAL DEFAULT 0,
+            loss15m REAL DEFAULT 0,
+            loss6h REAL DEFAULT 0,
+            loss24h REAL DEFAULT 0,
+            late INTEGER NOT NULL DEFAULT 0,
+            duplicates INTEGER NOT NULL DEFAULT 0,
+            reordered INTEGER NOT NULL DEFAULT 0,
+            corrupted INTEGER NOT NULL DEFAULT 0,
+            ttl INTEGER NOT NULL DEFAULT 0,
+            hops INTEGER NOT NULL DEFAULT 0,
+            address VARCHAR(45) NOT NULL DEFAULT '',
+            payload_size INTEGER NOT NULL DEFAULT 0,
+            dscp INTEGER NOT NULL DEFAULT 0,
+            dont_fragment INTEGER NOT NULL DEFAULT 0,
             timestamp INTEGER NOT NULL
         );`,
 
 		`CREATE TABLE IF NOT EXISTS losses (
             target_uuid CHAR(36) NOT NULL,
             timestamp INTEGER NOT NULL,
+            rcode TEXT NOT NULL DEFAULT '',
+            reason TEXT NOT NULL DEFAULT '',
+            icmp_type INTEGER NOT NULL DEFAULT 0,
+            icmp_code INTEGER NOT NULL DEFAULT 0,
+            payload_size INTEGER NOT NULL DEFAULT 0,
+            dscp INTEGER NOT NULL DEFAULT 0,
+            dont_fragment INTEGER NOT NULL DEFAULT 0,
+            address VARCHAR(45) NOT NULL DEFAULT '',
             PRIMARY KEY (target_uuid, timestamp)
         );`,
 
@@ -378,6 +1164,12 @@ func InitializeSQLiteDB(db *sql.DB) error {
             target_uuid CHAR(36) NOT NULL,
             timestamp INTEGER NOT NULL,
             latency REAL NOT NULL,
+            rcode TEXT NOT NULL DEFAULT '',
+            ttl INTEGER NOT NULL DEFAULT 0,
+            payload_size INTEGER NOT NULL DEFAULT 0,
+            dscp INTEGER NOT NULL DEFAULT 0,
+            dont_fragment INTEGER NOT NULL DEFAULT 0,
+            address VARCHAR(45) NOT NULL DEFAULT '',
             PRIMARY KEY (target_uuid, timestamp)
         );`,
 
@@ -388,6 +1180,141 @@ func InitializeSQLiteDB(db *sql.DB) error {
             count INTEGER DEFAULT 1,
             PRIMARY KEY (target_uuid, timestamp, bucket)
         );`,
+
+		`CREATE TABLE IF NOT EXISTS http_timings (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            dns REAL NOT NULL DEFAULT 0,
+            connect REAL NOT NULL DEFAULT 0,
+            tls REAL NOT NULL DEFAULT 0,
+            ttfb REAL NOT NULL DEFAULT 0,
+            PRIMARY KEY (target_uuid, timestamp)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS hops (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            hop INTEGER NOT NULL,
+            address TEXT NOT NULL DEFAULT '',
+            sent INTEGER NOT NULL DEFAULT 0,
+            recv INTEGER NOT NULL DEFAULT 0,
+            latency REAL NOT NULL DEFAULT 0,
+            PRIMARY KEY (target_uuid, timestamp, hop)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS jitters (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            jitter REAL NOT NULL,
+            payload_size INTEGER NOT NULL DEFAULT 0,
+            dscp INTEGER NOT NULL DEFAULT 0,
+            dont_fragment INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (target_uuid, timestamp)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS settings (
+            setting TEXT NOT NULL,
+            value TEXT NOT NULL,
+            PRIMARY KEY (setting)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS incidents (
+            target_uuid CHAR(36) NOT NULL,
+            start_time INTEGER NOT NULL,
+            end_time INTEGER NOT NULL DEFAULT 0,
+            duration INTEGER NOT NULL DEFAULT 0,
+            packets INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (target_uuid, start_time)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS state_changes (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            state TEXT NOT NULL,
+            previous TEXT NOT NULL DEFAULT '',
+            PRIMARY KEY (target_uuid, timestamp)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS late_replies (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            seq INTEGER NOT NULL,
+            latency REAL NOT NULL,
+            PRIMARY KEY (target_uuid, timestamp, seq)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS packet_events (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            kind TEXT NOT NULL,
+            count INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (target_uuid, timestamp, kind)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS path_changes (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            previous_hops INTEGER NOT NULL,
+            hops INTEGER NOT NULL,
+            PRIMARY KEY (target_uuid, timestamp)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS path_mtus (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            mtu INTEGER NOT NULL,
+            PRIMARY KEY (target_uuid, timestamp)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS mtu_changes (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            previous_mtu INTEGER NOT NULL,
+            mtu INTEGER NOT NULL,
+            PRIMARY KEY (target_uuid, timestamp)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS family_results (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            family TEXT NOT NULL,
+            address TEXT NOT NULL DEFAULT '',
+            latency REAL NOT NULL DEFAULT 0,
+            lost INTEGER NOT NULL DEFAULT 0,
+            reason TEXT NOT NULL DEFAULT '',
+            payload_size INTEGER NOT NULL DEFAULT 0,
+            dscp INTEGER NOT NULL DEFAULT 0,
+            dont_fragment INTEGER NOT NULL DEFAULT 0,
+            PRIMARY KEY (target_uuid, timestamp, family)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS family_statistics (
+            target_uuid CHAR(36) NOT NULL,
+            family TEXT NOT NULL,
+            address TEXT NOT NULL DEFAULT '',
+            sent INTEGER NOT NULL DEFAULT 0,
+            recv INTEGER NOT NULL DEFAULT 0,
+            last REAL NOT NULL DEFAULT 0,
+            min REAL NOT NULL DEFAULT 0,
+            max REAL NOT NULL DEFAULT 0,
+            avg15m REAL NOT NULL DEFAULT 0,
+            avg6h REAL NOT NULL DEFAULT 0,
+            avg24h REAL NOT NULL DEFAULT 0,
+            loss15m REAL NOT NULL DEFAULT 0,
+            loss6h REAL NOT NULL DEFAULT 0,
+            loss24h REAL NOT NULL DEFAULT 0,
+            timestamp INTEGER NOT NULL,
+            PRIMARY KEY (target_uuid, family)
+        );`,
+
+		`CREATE TABLE IF NOT EXISTS resolution_changes (
+            target_uuid CHAR(36) NOT NULL,
+            timestamp INTEGER NOT NULL,
+            family VARCHAR(4) NOT NULL DEFAULT '',
+            previous_address VARCHAR(45) NOT NULL,
+            address VARCHAR(45) NOT NULL,
+            PRIMARY KEY (target_uuid, timestamp, family)
+        );`,
 	}
 
 	for _, query := range queries {
@@ -398,5 +1325,18 @@ func InitializeSQLiteDB(db *sql.DB) error {
 		}
 	}
 
-	return nil
+	err := migrateColumns(db, func(table, name string) (bool, error) {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, name).Scan(&count)
+		return count > 0, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return migrateIndexes(db, func(table, name string) (bool, error) {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?", table, name).Scan(&count)
+		return count > 0, err
+	})
 }
@@ -0,0 +1,32 @@
+package model
+
+// Batch holds the statistics and results of many probes that are written in a single transaction
+type Batch struct {
+	Stats        []Stats
+	Latencies    []Latency
+	Losses       []Loss
+	Jitters      []Jitter
+	HTTPTimings  []HTTPTiming
+	Measurements []HistogramMeasurement
+	// HistogramResets are the targets whose histograms start over, because their
+	// probe settings changed. Their histograms are deleted before Measurements are written.
+	HistogramResets []string
+
+	StateChanges      []StateChange
+	Incidents         []Incident
+	LateReplies       []LateReply
+	PacketEvents      []PacketEvent
+	PathChanges       []PathChange
+	ResolutionChanges []ResolutionChange
+	FamilyResults     []FamilyResult
+	FamilyStats       []FamilyStats
+}
+
+// Empty reports whether there is nothing to write
+func (b *Batch) Empty() bool {
+	return len(b.Stats) == 0 && len(b.Latencies) == 0 && len(b.Losses) == 0 &&
+		len(b.Jitters) == 0 && len(b.HTTPTimings) == 0 && len(b.Measurements) == 0 &&
+		len(b.HistogramResets) == 0 && len(b.StateChanges) == 0 && len(b.Incidents) == 0 &&
+		len(b.LateReplies) == 0 && len(b.PacketEvents) == 0 && len(b.PathChanges) == 0 &&
+		len(b.ResolutionChanges) == 0 && len(b.FamilyResults) == 0 && len(b.FamilyStats) == 0
+}
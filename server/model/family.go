@@ -0,0 +1,41 @@
+package model
+
+// Address families of dual-stack targets
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// Families are the address families a dual-stack target is probed over
+var Families = []string{FamilyIPv4, FamilyIPv6}
+
+// FamilyResult is the outcome of a probe over one address family of a dual-stack target
+type FamilyResult struct {
+	TargetUuid string  `json:"target_uuid"`
+	Timestamp  int64   `json:"timestamp"`
+	Family     string  `json:"family"`
+	Address    string  `json:"address"` // empty if the family has no address
+	Latency    float64 `json:"latency"` // 0 if the probe was lost
+	Lost       bool    `json:"lost"`
+	Reason     string  `json:"reason"` // why the probe was lost, see Reason*
+	ProbeSettings
+}
+
+// FamilyStats are the statistics of one address family of a dual-stack target
+type FamilyStats struct {
+	TargetUuid string  `json:"target_uuid"`
+	Family     string  `json:"family"`
+	Address    string  `json:"address"` // the address that was probed last
+	Sent       uint64  `json:"sent"`
+	Recv       uint64  `json:"recv"`
+	Last       float64 `json:"last"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+	Avg15m     float64 `json:"avg15m"`
+	Avg6h      float64 `json:"avg6h"`
+	Avg24h     float64 `json:"avg24h"`
+	Loss15m    float64 `json:"loss15m"` // Packet loss in percent, as moving average like Avg15m
+	Loss6h     float64 `json:"loss6h"`
+	Loss24h    float64 `json:"loss24h"`
+	Timestamp  int64   `json:"timestamp"`
+}
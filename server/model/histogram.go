@@ -1,8 +1,74 @@
 package model
 
+import (
+	"fmt"
+	"math"
+)
+
 type HistogramMeasurement struct {
 	TargetUuid string  `json:"target_uuid"`
 	Timestamp  int64   `json:"timestamp"`
 	Bucket     float64 `json:"bucket"`
 	Count      int64   `json:"count"`
 }
+
+// Bucket kinds of a BucketScheme
+const (
+	BucketsLog2   = "log2"
+	BucketsLinear = "linear"
+)
+
+// BucketScheme defines how latencies are grouped into histogram buckets.
+type BucketScheme struct {
+	// Kind is BucketsLog2 or BucketsLinear
+	Kind string
+	// Resolution is the number of buckets per doubling of the latency for log2
+	// buckets (meshping uses 10) or the width of a bucket in milliseconds for
+	// linear buckets.
+	Resolution float64
+	// Width is the duration of a time bucket in seconds
+	Width int64
+}
+
+// DefaultBucketScheme matches the buckets of meshping: log2(latency) * 10, one hour wide
+var DefaultBucketScheme = BucketScheme{Kind: BucketsLog2, Resolution: 10, Width: 3600}
+
+func (s BucketScheme) Validate() error {
+	if s.Kind != BucketsLog2 && s.Kind != BucketsLinear {
+		return fmt.Errorf("unknown histogram bucket kind %q", s.Kind)
+	}
+	if s.Resolution <= 0 {
+		return fmt.Errorf("histogram resolution must be greater than 0")
+	}
+	if s.Width <= 0 {
+		return fmt.Errorf("histogram width must be greater than 0")
+	}
+	return nil
+}
+
+// String identifies the scheme, stored histograms have to be rebucketed if it changes
+func (s BucketScheme) String() string {
+	return fmt.Sprintf("%s/%g/%d", s.Kind, s.Resolution, s.Width)
+}
+
+// Bucket returns the lower bound in milliseconds of the bucket that contains latency
+func (s BucketScheme) Bucket(latency float64) float64 {
+	if latency <= 0 {
+		return 0
+	}
+
+	switch s.Kind {
+	case BucketsLinear:
+		return math.Floor(latency/s.Resolution+1e-9) * s.Resolution
+	default:
+		// The epsilon makes sure that the lower bound of a bucket maps to the bucket
+		// itself, so stored buckets can be rebucketed with the same function.
+		index := math.Floor(math.Log2(latency)*s.Resolution + 1e-9)
+		return math.Pow(2, index/s.Resolution)
+	}
+}
+
+// Timestamp returns the start of the time bucket that contains timestamp
+func (s BucketScheme) Timestamp(timestamp int64) int64 {
+	return timestamp / s.Width * s.Width
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBucketScheme_Log2(t *testing.T) {
+	scheme := DefaultBucketScheme
+
+	// 10 buckets per doubling, so every bucket is about 7% wide
+	tests := map[float64]float64{
+		1:    1,
+		1.05: 1,
+		1.08: math.Pow(2, 0.1),
+		16:   16,
+		20.5: math.Pow(2, 43.0/10),
+	}
+	for latency, want := range tests {
+		if got := scheme.Bucket(latency); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Bucket(%v) = %v, want %v", latency, got, want)
+		}
+	}
+
+	if got := scheme.Bucket(0); got != 0 {
+		t.Errorf("Bucket(0) = %v, want 0", got)
+	}
+}
+
+func TestBucketScheme_BucketIsStable(t *testing.T) {
+	// Rebucketing a stored bucket with the same scheme must not move it
+	for _, scheme := range []BucketScheme{DefaultBucketScheme, {Kind: BucketsLog2, Resolution: 3, Width: 60}, {Kind: BucketsLinear, Resolution: 0.1, Width: 60}} {
+		for latency := 0.01; latency < 2000; latency *= 1.013 {
+			bucket := scheme.Bucket(latency)
+			if again := scheme.Bucket(bucket); again != bucket {
+				t.Fatalf("%v: Bucket(%v) = %v, but Bucket(%v) = %v", scheme, latency, bucket, bucket, again)
+			}
+		}
+	}
+}
+
+func TestBucketScheme_Linear(t *testing.T) {
+	scheme := BucketScheme{Kind: BucketsLinear, Resolution: 5, Width: 600}
+
+	if got := scheme.Bucket(12.3); got != 10 {
+		t.Errorf("Bucket(12.3) = %v, want 10", got)
+	}
+	if got := scheme.Timestamp(1700000123); got != 1699999800 {
+		t.Errorf("Timestamp(1700000123) = %v, want 1699999800", got)
+	}
+}
+
+func TestBucketScheme_Validate(t *testing.T) {
+	if err := DefaultBucketScheme.Validate(); err != nil {
+		t.Errorf("default scheme is invalid: %v", err)
+	}
+
+	invalid := []BucketScheme{
+		{Kind: "cubic", Resolution: 10, Width: 3600},
+		{Kind: BucketsLog2, Resolution: 0, Width: 3600},
+		{Kind: BucketsLinear, Resolution: 1, Width: 0},
+	}
+	for _, scheme := range invalid {
+		if err := scheme.Validate(); err == nil {
+			t.Errorf("%v should be invalid", scheme)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+// Hop is the result of the TTL limited probes to one hop of the path to a target
+type Hop struct {
+	TargetUuid string `json:"target_uuid"`
+	Timestamp  int64  `json:"timestamp"`
+	// Hop is the TTL of the probes, starting with 1
+	Hop int `json:"hop"`
+	// Address of the router that answered, empty if no probe got a reply
+	Address string `json:"address"`
+	Sent    int    `json:"sent"`
+	Recv    int    `json:"recv"`
+	// Latency is the average round trip time of all replies in milliseconds
+	Latency float64 `json:"latency"`
+}
+
+// Path is the list of hops that was discovered at Timestamp
+type Path struct {
+	Timestamp int64 `json:"timestamp"`
+	Hops      []Hop `json:"hops"`
+}
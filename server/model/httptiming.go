@@ -0,0 +1,12 @@
+package model
+
+// HTTPTiming holds the duration of each phase of an HTTP(S) probe in milliseconds.
+// Phases that did not happen (e.g. TLS for plain HTTP) are 0.
+type HTTPTiming struct {
+	TargetUuid string  `json:"target_uuid"`
+	Timestamp  int64   `json:"timestamp"`
+	DNS        float64 `json:"dns"`
+	Connect    float64 `json:"connect"`
+	TLS        float64 `json:"tls"`
+	TTFB       float64 `json:"ttfb"`
+}
@@ -0,0 +1,13 @@
+package model
+
+// Incident is an outage of a target, from the first lost probe until the next reply
+type Incident struct {
+	TargetUuid string `json:"target_uuid"`
+	Start      int64  `json:"start"`
+	// End is 0 while the outage is ongoing
+	End int64 `json:"end"`
+	// Duration in seconds, until the last lost probe while the outage is ongoing
+	Duration int64 `json:"duration"`
+	// Packets is the number of packets that were lost during the outage
+	Packets int64 `json:"packets"`
+}
@@ -0,0 +1,8 @@
+package model
+
+type Jitter struct {
+	TargetUuid string  `json:"target_uuid"`
+	Timestamp  int64   `json:"timestamp"`
+	Jitter     float64 `json:"jitter"`
+	ProbeSettings
+}
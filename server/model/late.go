@@ -0,0 +1,11 @@
+package model
+
+// LateReply is an echo reply that arrived after the timeout of its request
+type LateReply struct {
+	TargetUuid string `json:"target_uuid"`
+	Timestamp  int64  `json:"timestamp"`
+	// Seq is the position of the request in its burst
+	Seq int `json:"seq"`
+	// Latency is the real round trip time in milliseconds
+	Latency float64 `json:"latency"`
+}
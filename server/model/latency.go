@@ -4,4 +4,10 @@ type Latency struct {
 	TargetUuid string  `json:"target_uuid"`
 	Timestamp  int64   `json:"timestamp"`
 	Latency    float64 `json:"latency"`
+	Rcode      string  `json:"rcode"`
+	// TTL of the echo reply, 0 for other probe kinds
+	TTL int `json:"ttl"`
+	// Address is the IP address the probe was sent to, empty if unknown
+	Address string `json:"address"`
+	ProbeSettings
 }
@@ -1,6 +1,27 @@
 package model
 
+// Reasons why a probe was lost
+const (
+	ReasonTimeout      = "timeout"       // no answer within the timeout
+	ReasonResolve      = "resolve"       // the hostname could not be resolved
+	ReasonSend         = "send"          // the probe could not be sent, e.g. no local route
+	ReasonUnreachable  = "unreachable"   // ICMP destination unreachable
+	ReasonTimeExceeded = "time_exceeded" // ICMP time exceeded, e.g. a routing loop
+	ReasonRefused      = "refused"       // the TCP connection was refused
+	ReasonStatus       = "status"        // the HTTP server responded with an error status
+	ReasonRcode        = "rcode"         // the DNS resolver responded with an error code
+	ReasonError        = "error"         // any other error
+)
+
 type Loss struct {
 	TargetUuid string `json:"target_uuid"`
 	Timestamp  int64  `json:"timestamp"`
+	Rcode      string `json:"rcode"`
+	Reason     string `json:"reason"`
+	// ICMPType and ICMPCode of the ICMP error that was received instead of a reply, 0 if there was none
+	ICMPType int `json:"icmp_type"`
+	ICMPCode int `json:"icmp_code"`
+	// Address is the IP address the probe was sent to, empty if the hostname could not be resolved
+	Address string `json:"address"`
+	ProbeSettings
 }
@@ -0,0 +1,17 @@
+package model
+
+// Kinds of packet events
+const (
+	EventDuplicate = "duplicate"
+	EventReordered = "reordered"
+	EventCorrupted = "corrupted"
+)
+
+// PacketEvent counts the echo replies of a probe that were duplicated,
+// reordered or corrupted on the way. Typical symptoms of bad Wi-Fi and broken middleboxes.
+type PacketEvent struct {
+	TargetUuid string `json:"target_uuid"`
+	Timestamp  int64  `json:"timestamp"`
+	Kind       string `json:"kind"`
+	Count      int    `json:"count"`
+}
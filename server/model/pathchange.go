@@ -0,0 +1,35 @@
+package model
+
+import "fmt"
+
+// PathChange is recorded when the number of hops to a target changes,
+// which means the route to it changed.
+type PathChange struct {
+	TargetUuid   string `json:"target_uuid"`
+	Timestamp    int64  `json:"timestamp"`
+	PreviousHops int    `json:"previous_hops"`
+	Hops         int    `json:"hops"`
+}
+
+// Kinds of annotations
+const (
+	AnnotationPathChanged    = "path_changed"
+	AnnotationMTUChanged     = "mtu_changed"
+	AnnotationAddressChanged = "address_changed"
+	AnnotationLatencyShifted = "latency_shifted"
+)
+
+// Annotation marks an event in a time series that helps to explain the latency
+type Annotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Kind      string `json:"kind"`
+	Text      string `json:"text"`
+}
+
+func (p PathChange) Annotation() Annotation {
+	return Annotation{
+		Timestamp: p.Timestamp,
+		Kind:      AnnotationPathChanged,
+		Text:      fmt.Sprintf("Path changed from %d to %d hops", p.PreviousHops, p.Hops),
+	}
+}
@@ -0,0 +1,28 @@
+package model
+
+import "fmt"
+
+// PathMTU is the largest packet in bytes, including the IP header, that reached a target
+// without being fragmented
+type PathMTU struct {
+	TargetUuid string `json:"target_uuid"`
+	Timestamp  int64  `json:"timestamp"`
+	MTU        int    `json:"mtu"`
+}
+
+// MTUChange is recorded when the path MTU to a target changes,
+// e.g. because the traffic is routed through a tunnel
+type MTUChange struct {
+	TargetUuid  string `json:"target_uuid"`
+	Timestamp   int64  `json:"timestamp"`
+	PreviousMTU int    `json:"previous_mtu"`
+	MTU         int    `json:"mtu"`
+}
+
+func (m MTUChange) Annotation() Annotation {
+	return Annotation{
+		Timestamp: m.Timestamp,
+		Kind:      AnnotationMTUChanged,
+		Text:      fmt.Sprintf("Path MTU changed from %d to %d bytes", m.PreviousMTU, m.MTU),
+	}
+}
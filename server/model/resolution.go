@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// A latency shift is flagged if the median latency around a resolution change
+// differs by at least ShiftMinimum milliseconds and ShiftRatio of the latency before it
+const (
+	ShiftWindow  = 15 * 60 // seconds before and after the change
+	ShiftMinimum = 5.0
+	ShiftRatio   = 0.2
+)
+
+// ResolutionChange is recorded when the host name of a target resolves to a different address,
+// e.g. because a CDN or matchmaking service moved it to another server
+type ResolutionChange struct {
+	TargetUuid      string `json:"target_uuid"`
+	Timestamp       int64  `json:"timestamp"`
+	Family          string `json:"family"` // address family of dual-stack targets, empty for other targets
+	PreviousAddress string `json:"previous_address"`
+	Address         string `json:"address"`
+}
+
+func (r ResolutionChange) Annotation() Annotation {
+	text := fmt.Sprintf("Address changed from %s to %s", r.PreviousAddress, r.Address)
+	if r.Family != "" {
+		text = fmt.Sprintf("%s address changed from %s to %s", strings.Replace(r.Family, "ip", "IP", 1), r.PreviousAddress, r.Address)
+	}
+	return Annotation{
+		Timestamp: r.Timestamp,
+		Kind:      AnnotationAddressChanged,
+		Text:      text,
+	}
+}
+
+// LatencyShift compares the median latency of the ShiftWindow before the change with the one after it.
+// latencies must belong to the target (and family) of the change. Returns false if the latency
+// did not shift or there are no latencies on one side of the change.
+func (r ResolutionChange) LatencyShift(latencies []Latency) (Annotation, bool) {
+	var before, after []float64
+	for _, l := range latencies {
+		switch {
+		case l.Timestamp >= r.Timestamp-ShiftWindow && l.Timestamp < r.Timestamp:
+			before = append(before, l.Latency)
+		case l.Timestamp >= r.Timestamp && l.Timestamp < r.Timestamp+ShiftWindow:
+			after = append(after, l.Latency)
+		}
+	}
+	if len(before) == 0 || len(after) == 0 {
+		return Annotation{}, false
+	}
+
+	// The median ignores single spikes right after the change
+	from, to := NewWindow(before).P50, NewWindow(after).P50
+	if math.Abs(to-from) < max(ShiftMinimum, from*ShiftRatio) {
+		return Annotation{}, false
+	}
+
+	return Annotation{
+		Timestamp: r.Timestamp,
+		Kind:      AnnotationLatencyShifted,
+		Text:      fmt.Sprintf("Latency shifted from %.1f ms to %.1f ms with the new address %s", from, to, r.Address),
+	}, true
+}
@@ -0,0 +1,37 @@
+package model
+
+import "testing"
+
+func TestResolutionChange_LatencyShift(t *testing.T) {
+	change := ResolutionChange{Timestamp: 10000, PreviousAddress: "192.0.2.1", Address: "192.0.2.2"}
+
+	latencies := func(before, after float64) []Latency {
+		var l []Latency
+		for ts := int64(9000); ts < 11000; ts += 100 {
+			latency := before
+			if ts >= change.Timestamp {
+				latency = after
+			}
+			l = append(l, Latency{Timestamp: ts, Latency: latency})
+		}
+		return l
+	}
+
+	annotation, ok := change.LatencyShift(latencies(20, 45))
+	if !ok || annotation.Kind != AnnotationLatencyShifted {
+		t.Errorf("LatencyShift() = %+v, %v, want a latency_shifted annotation", annotation, ok)
+	}
+
+	// 2 ms are below the minimum, 8 ms on 100 ms are below the ratio
+	if _, ok := change.LatencyShift(latencies(20, 22)); ok {
+		t.Error("LatencyShift() flagged a shift of 2 ms")
+	}
+	if _, ok := change.LatencyShift(latencies(100, 108)); ok {
+		t.Error("LatencyShift() flagged a shift of 8%")
+	}
+
+	// Without latencies after the change there is nothing to compare
+	if _, ok := change.LatencyShift(latencies(20, 45)[:5]); ok {
+		t.Error("LatencyShift() flagged a shift without latencies after the change")
+	}
+}
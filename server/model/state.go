@@ -0,0 +1,18 @@
+package model
+
+// States of a target
+const (
+	StateUp       = "up"
+	StateDown     = "down"
+	StateDegraded = "degraded"
+	StateFlapping = "flapping"
+)
+
+// StateChange is a transition of a target from one state to another
+type StateChange struct {
+	TargetUuid string `json:"target_uuid"`
+	Timestamp  int64  `json:"timestamp"`
+	State      string `json:"state"`
+	// Previous is empty for the first state of a target
+	Previous string `json:"previous"`
+}
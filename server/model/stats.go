@@ -15,5 +15,29 @@ type Stats struct {
 	Avg15m     float64         `json:"avg15m"`
 	Avg6h      float64         `json:"avg6h"`
 	Avg24h     float64         `json:"avg24h"`
+	Jitter     float64         `json:"jitter"`     // RFC 3550 interarrival jitter of the round trip times
+	BurstMin   float64         `json:"burst_min"`  // Min latency of the last burst
+	BurstAvg   float64         `json:"burst_avg"`  // Average latency of the last burst
+	BurstMax   float64         `json:"burst_max"`  // Max latency of the last burst
+	BurstLoss  float64         `json:"burst_loss"` // Packet loss of the last burst in percent
+	Loss15m    float64         `json:"loss15m"`    // Packet loss in percent, as moving average like Avg15m
+	Loss6h     float64         `json:"loss6h"`
+	Loss24h    float64         `json:"loss24h"`
+	RFactor15m float64         `json:"rfactor15m"` // E-model transmission rating (0 - 100)
+	RFactor6h  float64         `json:"rfactor6h"`
+	RFactor24h float64         `json:"rfactor24h"`
+	Mos15m     float64         `json:"mos15m"` // Mean opinion score (1 - 4.5) derived from the R-factor
+	Mos6h      float64         `json:"mos6h"`
+	Mos24h     float64         `json:"mos24h"`
+	Late       uint64          `json:"late"`       // Replies that arrived after the timeout, they count as received and as latency
+	Duplicates uint64          `json:"duplicates"` // Echo replies that were received more than once
+	Reordered  uint64          `json:"reordered"`  // Echo replies that arrived after a reply to a later request
+	Corrupted  uint64          `json:"corrupted"`  // Echo replies whose payload differs from the request
+	TTL        int             `json:"ttl"`        // TTL of the last echo reply, 0 if unknown
+	Hops       int             `json:"hops"`       // Number of hops to the target, inferred from the TTL
+	Address    string          `json:"address"`    // IP address the last probe was sent to
 	Timestamp  int64           `json:"timestamp"`
+
+	// ProbeSettings the statistics were measured with, they start over when the settings change
+	ProbeSettings
 }
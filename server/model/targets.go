@@ -1,7 +1,163 @@
 package model
 
+import "time"
+
+// Defaults for targets without their own probe interval or timeout
+const (
+	DefaultInterval = 15    // seconds
+	DefaultTimeout  = 10000 // milliseconds
+	DefaultCount    = 1
+	DefaultSpacing  = 100 // milliseconds
+
+	DefaultFailThreshold    = 3
+	DefaultSuccessThreshold = 2
+	DefaultSlowThreshold    = 3
+	DefaultFastThreshold    = 2
+
+	DefaultPayloadSize = 56    // bytes, the same as ping uses
+	MaxPayloadSize     = 65507 // bytes, the largest ICMP echo request that fits into an IPv4 packet
+	MaxDSCP            = 63
+)
+
+// Probe kinds a target can be measured with
+const (
+	ProbeICMP = "icmp"
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeDNS  = "dns"
+)
+
 type Target struct {
 	Uuid    string `json:"uuid"`
 	Name    string `json:"name"`
 	Address string `json:"address"`
+	Probe   string `json:"probe"`
+	Port    int    `json:"port"`
+	// QueryName and RecordType are the question of DNS probes,
+	// Address is the resolver that gets asked.
+	QueryName  string `json:"query_name"`
+	RecordType string `json:"record_type"`
+	// Traceroute enables the periodic discovery of the path to the target
+	Traceroute bool `json:"traceroute"`
+	// MTUDiscovery enables the periodic discovery of the path MTU to the target
+	MTUDiscovery bool `json:"mtu_discovery"`
+	// Interval between two probes in seconds
+	Interval int `json:"interval"`
+	// Timeout of a single probe in milliseconds
+	Timeout int `json:"timeout"`
+	// Count is the number of ICMP echo requests that are sent every interval
+	Count int `json:"count"`
+	// Spacing between the echo requests of a burst in milliseconds
+	Spacing int `json:"spacing"`
+	// FailThreshold is the number of consecutive lost probes until the target is down
+	FailThreshold int `json:"fail_threshold"`
+	// SuccessThreshold is the number of consecutive replies until the target is up again
+	SuccessThreshold int `json:"success_threshold"`
+	// DegradedLatency in milliseconds, above it the target is degraded. 0 disables it.
+	DegradedLatency float64 `json:"degraded_latency"`
+	// SlowThreshold is the number of consecutive slow replies until the target is degraded
+	SlowThreshold int `json:"slow_threshold"`
+	// FastThreshold is the number of consecutive fast replies until the target is no longer degraded
+	FastThreshold int `json:"fast_threshold"`
+	// PayloadSize of the ICMP echo requests in bytes, 0 for the default
+	PayloadSize int `json:"payload_size"`
+	// DSCP the ICMP echo requests are marked with, to test whether QoS is honoured
+	DSCP int `json:"dscp"`
+	// DontFragment sets the DF flag, so packets larger than the path MTU are dropped
+	// instead of fragmented. Without it the operating system decides.
+	DontFragment bool `json:"dont_fragment"`
+	// SourceAddress is the local IP the probes are sent from, empty for the default route
+	SourceAddress string `json:"source_address"`
+	// Interface binds the probes to a network interface, e.g. to compare several uplinks
+	Interface string `json:"interface"`
+	// Netns is the name of the Linux network namespace in /var/run/netns the probes run in,
+	// empty for the namespace of Lagident
+	Netns string `json:"netns"`
+	// DualStack probes the IPv4 and the IPv6 address of a host name as two separate series
+	DualStack bool `json:"dual_stack"`
+}
+
+// ProbeSettings are the settings of a target that change the packets of a probe.
+// They are stored with every result, so results with different settings are never mixed.
+type ProbeSettings struct {
+	PayloadSize  int  `json:"payload_size"`
+	DSCP         int  `json:"dscp"`
+	DontFragment bool `json:"dont_fragment"`
+}
+
+// Settings returns the probe settings of the target
+func (t Target) Settings() ProbeSettings {
+	return ProbeSettings{PayloadSize: t.PayloadSize, DSCP: t.DSCP, DontFragment: t.DontFragment}
+}
+
+// PayloadBytes returns the payload size of the ICMP echo requests or the default if none is set
+func (t Target) PayloadBytes() int {
+	if t.PayloadSize <= 0 {
+		return DefaultPayloadSize
+	}
+	return t.PayloadSize
+}
+
+// IntervalDuration returns the probe interval or the default if none is set
+func (t Target) IntervalDuration() time.Duration {
+	if t.Interval <= 0 {
+		return DefaultInterval * time.Second
+	}
+	return time.Duration(t.Interval) * time.Second
+}
+
+// TimeoutDuration returns the probe timeout or the default if none is set
+func (t Target) TimeoutDuration() time.Duration {
+	if t.Timeout <= 0 {
+		return DefaultTimeout * time.Millisecond
+	}
+	return time.Duration(t.Timeout) * time.Millisecond
+}
+
+// BurstCount returns the number of echo requests per burst, at least one
+func (t Target) BurstCount() int {
+	if t.Count <= 0 {
+		return DefaultCount
+	}
+	return t.Count
+}
+
+// SpacingDuration returns the time between two echo requests of a burst
+func (t Target) SpacingDuration() time.Duration {
+	if t.Spacing <= 0 {
+		return DefaultSpacing * time.Millisecond
+	}
+	return time.Duration(t.Spacing) * time.Millisecond
+}
+
+// Failures returns the number of consecutive lost probes until the target is down
+func (t Target) Failures() int {
+	if t.FailThreshold <= 0 {
+		return DefaultFailThreshold
+	}
+	return t.FailThreshold
+}
+
+// Successes returns the number of consecutive replies until the target is up again
+func (t Target) Successes() int {
+	if t.SuccessThreshold <= 0 {
+		return DefaultSuccessThreshold
+	}
+	return t.SuccessThreshold
+}
+
+// SlowReplies returns the number of consecutive slow replies until the target is degraded
+func (t Target) SlowReplies() int {
+	if t.SlowThreshold <= 0 {
+		return DefaultSlowThreshold
+	}
+	return t.SlowThreshold
+}
+
+// FastReplies returns the number of consecutive fast replies until the target is no longer degraded
+func (t Target) FastReplies() int {
+	if t.FastThreshold <= 0 {
+		return DefaultFastThreshold
+	}
+	return t.FastThreshold
 }
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// Window summarizes the latencies of a target within a time window
+type Window struct {
+	Samples int     `json:"samples"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+}
+
+// NewWindow summarizes latencies, the order does not matter
+func NewWindow(latencies []float64) Window {
+	w := Window{Samples: len(latencies)}
+	if len(latencies) == 0 {
+		return w
+	}
+
+	sorted := make([]float64, len(latencies))
+	copy(sorted, latencies)
+	sort.Float64s(sorted)
+
+	w.Min = sorted[0]
+	w.Max = sorted[len(sorted)-1]
+	w.P50 = Percentile(sorted, 50)
+	w.P95 = Percentile(sorted, 95)
+	w.P99 = Percentile(sorted, 99)
+	return w
+}
+
+// NewRankedWindow summarizes samples latencies of which only the ranks a Window needs are known,
+// ranked maps the position in the sorted latencies to the latency. Needed are the first and the
+// last position and the two positions around p * (samples - 1) / 100 for every percentile.
+// It lets the database summarize the latencies without returning all of them.
+func NewRankedWindow(samples int, ranked map[int]float64) Window {
+	w := Window{Samples: samples}
+	if samples == 0 {
+		return w
+	}
+
+	at := func(i int) float64 { return ranked[i] }
+	w.Min = at(0)
+	w.Max = at(samples - 1)
+	w.P50 = percentileAt(samples, at, 50)
+	w.P95 = percentileAt(samples, at, 95)
+	w.P99 = percentileAt(samples, at, 99)
+	return w
+}
+
+// Percentile returns the p-th percentile (0 - 100) of sorted values.
+// Values between two samples are interpolated linearly.
+func Percentile(sorted []float64, p float64) float64 {
+	return percentileAt(len(sorted), func(i int) float64 { return sorted[i] }, p)
+}
+
+// percentileAt returns the p-th percentile of n sorted values, at returns the i-th value
+func percentileAt(n int, at func(i int) float64, p float64) float64 {
+	if n == 0 {
+		return 0
+	}
+
+	rank := p * float64(n-1) / 100
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if upper >= n {
+		return at(n - 1)
+	}
+
+	return at(lower) + (at(upper)-at(lower))*(rank-float64(lower))
+}
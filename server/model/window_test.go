@@ -0,0 +1,74 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewWindow(t *testing.T) {
+	w := NewWindow([]float64{12.5, 3.2, 40.1, 8})
+	if w.Samples != 4 || w.Min != 3.2 || w.Max != 40.1 {
+		t.Errorf("NewWindow() = %+v, want 4 samples, min 3.2 and max 40.1", w)
+	}
+
+	w = NewWindow(nil)
+	if w.Samples != 0 || w.Min != 0 || w.Max != 0 || w.P99 != 0 {
+		t.Errorf("NewWindow(nil) = %+v, want an empty window", w)
+	}
+}
+
+func TestNewWindow_Percentiles(t *testing.T) {
+	// 1 to 100 in reverse order
+	latencies := make([]float64, 100)
+	for i := range latencies {
+		latencies[i] = float64(100 - i)
+	}
+
+	w := NewWindow(latencies)
+	if math.Abs(w.P50-50.5) > 1e-9 || math.Abs(w.P95-95.05) > 1e-9 || math.Abs(w.P99-99.01) > 1e-9 {
+		t.Errorf("NewWindow() = %+v, want p50 50.5, p95 95.05 and p99 99.01", w)
+	}
+
+	// The input must not be reordered
+	if latencies[0] != 100 {
+		t.Errorf("NewWindow() modified the latencies")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40}
+
+	tests := map[float64]float64{0: 10, 50: 25, 100: 40, 99: 39.7}
+	for p, want := range tests {
+		if got := Percentile(sorted, p); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+
+	if got := Percentile([]float64{7}, 95); got != 7 {
+		t.Errorf("Percentile of a single value = %v, want 7", got)
+	}
+}
+
+func TestNewRankedWindow(t *testing.T) {
+	latencies := make([]float64, 37)
+	for i := range latencies {
+		latencies[i] = float64(i*i) / 3
+	}
+
+	// Only the positions the database returns
+	ranked := map[int]float64{0: latencies[0], 36: latencies[36]}
+	for _, p := range []int{50, 95, 99} {
+		lower, upper := p*36/100, (p*36+99)/100
+		ranked[lower], ranked[upper] = latencies[lower], latencies[upper]
+	}
+
+	want := NewWindow(latencies)
+	if got := NewRankedWindow(len(latencies), ranked); got != want {
+		t.Errorf("NewRankedWindow() = %+v, want %+v", got, want)
+	}
+
+	if got := NewRankedWindow(0, nil); got != (Window{}) {
+		t.Errorf("NewRankedWindow(0) = %+v, want an empty window", got)
+	}
+}
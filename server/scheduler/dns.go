@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lagident/model"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// recordTypes maps the record types a DNS target can query to their wire type
+var recordTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SOA":   dnsmessage.TypeSOA,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+// IsRecordType reports whether a DNS target can query records of type t
+func IsRecordType(t string) bool {
+	_, ok := recordTypes[strings.ToUpper(t)]
+	return ok
+}
+
+// DNSProber sends a query for target.QueryName and target.RecordType to the
+// resolver in target.Address and measures the time until the response arrives.
+type DNSProber struct{}
+
+func (DNSProber) Probe(ctx context.Context, target *model.Target, timeout time.Duration) Result {
+	qtype, ok := recordTypes[strings.ToUpper(target.RecordType)]
+	if !ok {
+		return Result{Lost: true, Err: fmt.Errorf("unsupported record type %q", target.RecordType)}
+	}
+
+	name, err := dnsmessage.NewName(dnsFQDN(target.QueryName))
+	if err != nil {
+		return Result{Lost: true, Err: err}
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	packet, err := query.Pack()
+	if err != nil {
+		return Result{Lost: true, Err: err}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := newDialer(target, "udp").DialContext(ctx, "udp", resolverAddress(target.Address))
+	if err != nil {
+		return Result{Lost: true, Err: err}
+	}
+	defer conn.Close()
+
+	address := remoteIP(conn.RemoteAddr())
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	start := time.Now()
+	if _, err := conn.Write(packet); err != nil {
+		return Result{Lost: true, Err: err, Address: address}
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return Result{Lost: true, Err: err, Address: address}
+		}
+		rtt := time.Since(start)
+
+		var response dnsmessage.Parser
+		header, err := response.Start(buf[:n])
+		if err != nil || !header.Response || header.ID != id {
+			// Not the answer to our query, keep waiting
+			continue
+		}
+
+		rcode := rcodeName(header.RCode)
+
+		// NXDOMAIN is a valid answer of a working resolver, everything else
+		// besides NOERROR means the resolver could not answer the query.
+		if header.RCode != dnsmessage.RCodeSuccess && header.RCode != dnsmessage.RCodeNameError {
+			return Result{Lost: true, Rcode: rcode, Reason: model.ReasonRcode, Err: errors.New("resolver responded with " + rcode), Address: address}
+		}
+
+		return Result{Latency: milliseconds(rtt), Rcode: rcode, Address: address}
+	}
+}
+
+// resolverAddress adds the default DNS port to address if it has none
+func resolverAddress(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), "53")
+}
+
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func rcodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	default:
+		return "RCODE" + strconv.Itoa(int(rcode))
+	}
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"lagident/model"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSStub answers every query with rcode after delay
+func startDNSStub(t *testing.T, rcode dnsmessage.RCode, delay time.Duration) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil {
+				continue
+			}
+
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: rcode},
+				Questions: query.Questions,
+			}
+			if rcode == dnsmessage.RCodeSuccess {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+				}}
+			}
+			packet, _ := response.Pack()
+
+			time.Sleep(delay)
+			conn.WriteTo(packet, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestDNSProber_Probe(t *testing.T) {
+	target := &model.Target{
+		Address:    startDNSStub(t, dnsmessage.RCodeSuccess, 10*time.Millisecond),
+		Probe:      model.ProbeDNS,
+		QueryName:  "example.com",
+		RecordType: "A",
+	}
+
+	result := DNSProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe was lost: %v", result.Err)
+	}
+	if result.Rcode != "NOERROR" {
+		t.Errorf("unexpected rcode: got %v want NOERROR", result.Rcode)
+	}
+	if result.Latency < 10 {
+		t.Errorf("latency should include the resolver delay, got %v", result.Latency)
+	}
+}
+
+func TestDNSProber_Probe_ServerFailure(t *testing.T) {
+	target := &model.Target{
+		Address:    startDNSStub(t, dnsmessage.RCodeServerFailure, 0),
+		Probe:      model.ProbeDNS,
+		QueryName:  "example.com",
+		RecordType: "AAAA",
+	}
+
+	result := DNSProber{}.Probe(context.Background(), target, time.Second)
+	if !result.Lost {
+		t.Errorf("SERVFAIL should be counted as loss")
+	}
+	if result.Rcode != "SERVFAIL" {
+		t.Errorf("unexpected rcode: got %v want SERVFAIL", result.Rcode)
+	}
+}
+
+func TestDNSProber_Probe_Timeout(t *testing.T) {
+	target := &model.Target{
+		Address:    startDNSStub(t, dnsmessage.RCodeSuccess, 500*time.Millisecond),
+		Probe:      model.ProbeDNS,
+		QueryName:  "example.com",
+		RecordType: "A",
+	}
+
+	result := DNSProber{}.Probe(context.Background(), target, 100*time.Millisecond)
+	if !result.Lost {
+		t.Errorf("probe should time out")
+	}
+	if result.Rcode != "" {
+		t.Errorf("timed out probe should not have an rcode, got %v", result.Rcode)
+	}
+}
+
+func TestResolverAddress(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.53":      "192.0.2.53:53",
+		"192.0.2.53:5353": "192.0.2.53:5353",
+		"2001:db8::53":    "[2001:db8::53]:53",
+		"[2001:db8::53]":  "[2001:db8::53]:53",
+		"dns.example.com": "dns.example.com:53",
+	}
+
+	for address, want := range tests {
+		if got := resolverAddress(address); got != want {
+			t.Errorf("resolverAddress(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// echoSocket sends echo requests to a single destination and reads the answers.
+// It uses a raw socket if possible, which also receives ICMP errors like
+// destination unreachable. Without the permission for raw sockets it falls back
+// to an unprivileged ping socket, which only receives echo replies.
+type echoSocket struct {
+	conn  *echoConn
+	dst   net.IP
+	proto int
+}
+
+// echoConn is an ICMP socket together with access to its IP level options
+type echoConn struct {
+	net.PacketConn
+	v4         *ipv4.PacketConn
+	v6         *ipv6.PacketConn
+	privileged bool
+}
+
+// socketOptions are the IP level options of an echo socket
+type socketOptions struct {
+	// tos is the type of service byte of IPv4 or the traffic class of IPv6
+	tos          int
+	dontFragment bool
+	// source is the local address and device the network interface the socket is bound to
+	source net.IP
+	device string
+	// netns is the network namespace the socket is created in
+	netns string
+}
+
+// echoAnswer is an echo reply or an ICMP error that answers one of our echo requests
+type echoAnswer struct {
+	seq      int
+	msg      *icmp.Message
+	from     net.IP
+	received time.Time
+	// ttl is the TTL or hop limit of the IP packet, 0 if unknown
+	ttl int
+}
+
+func openEchoSocket(dst net.IP, opts socketOptions) (*echoSocket, error) {
+	conn, err := openEchoConn(dst.To4() == nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &echoSocket{conn: conn, dst: dst, proto: icmpProtocol(dst)}, nil
+}
+
+// openEchoConn opens an ICMP socket with opts in the network namespace of opts
+func openEchoConn(v6 bool, opts socketOptions) (*echoConn, error) {
+	var conn *echoConn
+	err := inNetns(opts.netns, func() (err error) {
+		conn, err = listenEcho(v6, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The TTL of the replies tells how many hops away the target is
+	if v6 {
+		err = conn.v6.SetControlMessage(ipv6.FlagHopLimit, true)
+		if err == nil && opts.tos != 0 {
+			err = conn.v6.SetTrafficClass(opts.tos)
+		}
+	} else {
+		err = conn.v4.SetControlMessage(ipv4.FlagTTL, true)
+		if err == nil && opts.tos != 0 {
+			err = conn.v4.SetTOS(opts.tos)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// listenEcho opens a raw ICMP socket with opts, or a ping socket if that is not permitted
+func listenEcho(v6 bool, opts socketOptions) (*echoConn, error) {
+	network, address := "ip4:icmp", "0.0.0.0"
+	if v6 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+	if opts.source != nil {
+		address = opts.source.String()
+	}
+
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			return controlSocket(c, v6, opts)
+		},
+	}
+	conn, err := lc.ListenPacket(context.Background(), network, address)
+	if err == nil {
+		return newEchoConn(conn, v6, true), nil
+	}
+
+	ping, pingErr := listenPing(v6, opts)
+	if pingErr != nil {
+		// The error of the raw socket is more helpful, the ping socket is only a fallback
+		return nil, err
+	}
+	return ping, nil
+}
+
+func newEchoConn(conn net.PacketConn, v6, privileged bool) *echoConn {
+	c := &echoConn{PacketConn: conn, privileged: privileged}
+	if v6 {
+		c.v6 = ipv6.NewPacketConn(conn)
+	} else {
+		c.v4 = ipv4.NewPacketConn(conn)
+	}
+	return c
+}
+
+func (s *echoSocket) Close() error {
+	return s.conn.Close()
+}
+
+func (s *echoSocket) send(id, seq int, payload []byte) error {
+	return s.conn.sendEcho(s.dst, id, seq, payload)
+}
+
+// sendEcho sends an echo request to dst
+func (c *echoConn) sendEcho(dst net.IP, id, seq int, payload []byte) error {
+	msg := newEchoRequest(dst, id, seq, payload)
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	var addr net.Addr = &net.IPAddr{IP: dst}
+	if !c.privileged {
+		addr = &net.UDPAddr{IP: dst}
+	}
+	_, err = c.WriteTo(packet, addr)
+	return err
+}
+
+// read waits until deadline for the next answer to an echo request with id, or until ctx is done.
+// Ping sockets replace the id with their port, but only receive their own replies.
+func (s *echoSocket) read(ctx context.Context, buf []byte, id int, deadline time.Time) (echoAnswer, error) {
+	if err := s.conn.SetReadDeadline(deadline); err != nil {
+		return echoAnswer{}, err
+	}
+	// Moving the deadline to now interrupts the blocked read
+	stop := context.AfterFunc(ctx, func() { s.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	for {
+		n, ttl, peer, err := s.conn.readPacket(buf)
+		if ctx.Err() != nil {
+			return echoAnswer{}, ctx.Err()
+		}
+		if err != nil {
+			return echoAnswer{}, err
+		}
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(s.proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		replyID, seq, ok := echoReference(msg)
+		if !ok || (s.conn.privileged && replyID != id) {
+			continue
+		}
+
+		return echoAnswer{seq: seq, msg: msg, from: peerIP(peer), received: received, ttl: ttl}, nil
+	}
+}
+
+// readPacket reads the next ICMP message and the TTL or hop limit of its packet
+func (c *echoConn) readPacket(buf []byte) (n, ttl int, peer net.Addr, err error) {
+	if c.v6 != nil {
+		var cm *ipv6.ControlMessage
+		n, cm, peer, err = c.v6.ReadFrom(buf)
+		if cm != nil {
+			ttl = cm.HopLimit
+		}
+		return n, ttl, peer, err
+	}
+
+	var cm *ipv4.ControlMessage
+	n, cm, peer, err = c.v4.ReadFrom(buf)
+	if cm != nil {
+		ttl = cm.TTL
+	}
+	return n, ttl, peer, err
+}
+
+// peerIP returns the IP of the sender of a packet
+func peerIP(peer net.Addr) net.IP {
+	switch addr := peer.(type) {
+	case *net.IPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+	return nil
+}
+
+// icmpTypeCode returns the numeric type and code of msg
+func icmpTypeCode(msg *icmp.Message) (typ, code int) {
+	switch t := msg.Type.(type) {
+	case ipv4.ICMPType:
+		typ = int(t)
+	case ipv6.ICMPType:
+		typ = int(t)
+	}
+	return typ, msg.Code
+}
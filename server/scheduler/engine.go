@@ -0,0 +1,274 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// echoTransport sends the echo requests of a burst and reads their answers.
+// It is either a socket of its own or a share of a socket of the echoEngine.
+type echoTransport interface {
+	send(id, seq int, payload []byte) error
+	// read waits until deadline for the next answer to an echo request with id,
+	// or until ctx is done
+	read(ctx context.Context, buf []byte, id int, deadline time.Time) (echoAnswer, error)
+	Close() error
+}
+
+// echoEngine shares long-lived ICMP sockets between the ICMP probes of all targets,
+// one per address family and socket options. Opening a socket and a goroutine for
+// every probe does not scale to thousands of targets.
+//
+// Every echo request gets a sequence number that is unique within its socket, a single
+// goroutine per socket reads all answers and passes them on to the burst that waits for them.
+// The sequence number is used, not the id, because ping sockets replace the id with their port.
+//
+// A socket that no burst used for idleSocketTimeout is closed, so the sockets of deleted
+// targets and changed options do not stay open.
+type echoEngine struct {
+	mu      sync.Mutex
+	sockets map[string]*sharedSocket
+	closed  bool
+}
+
+// idleSocketTimeout is how long a socket of the echoEngine stays open without a burst
+const idleSocketTimeout = 5 * time.Minute
+
+// Delay between two reads of a socket that keeps failing
+const (
+	minReceiveBackoff = 10 * time.Millisecond
+	maxReceiveBackoff = time.Second
+)
+
+func newEchoEngine() *echoEngine {
+	return &echoEngine{sockets: make(map[string]*sharedSocket)}
+}
+
+// sharedSocket is an ICMP socket of the echoEngine
+type sharedSocket struct {
+	conn  *echoConn
+	proto int
+	// id of all echo requests sent by raw sockets
+	id int
+
+	// bursts is the number of bursts that use the socket, idle closes it once
+	// it had none for idleSocketTimeout. Both are guarded by the mutex of the engine.
+	bursts int
+	idle   *time.Timer
+
+	mu sync.Mutex
+	// waiting maps the sequence number on the wire to the burst and the sequence number within it
+	waiting map[uint16]echoWaiter
+	nextSeq uint16
+}
+
+// echoWaiter is an echo request that waits for its answer
+type echoWaiter struct {
+	burst *sharedBurst
+	seq   int
+}
+
+// sharedBurst is the share of a burst in a sharedSocket, it implements echoTransport
+type sharedBurst struct {
+	engine  *echoEngine
+	key     string
+	sock    *sharedSocket
+	dst     net.IP
+	answers chan echoAnswer
+	// wire holds the sequence numbers on the wire of the requests that were sent
+	wire []uint16
+}
+
+// socketKey identifies the sockets that can be shared
+func socketKey(v6 bool, opts socketOptions) string {
+	return fmt.Sprintf("%t/%d/%t/%s/%s/%s", v6, opts.tos, opts.dontFragment, opts.source, opts.device, opts.netns)
+}
+
+// burst returns the share of the socket for dst and opts for a burst of count echo requests.
+// The socket is opened on first use and kept open while bursts use it.
+func (e *echoEngine) burst(dst net.IP, opts socketOptions, count int) (*sharedBurst, error) {
+	v6 := dst.To4() == nil
+	key := socketKey(v6, opts)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, net.ErrClosed
+	}
+
+	sock, ok := e.sockets[key]
+	if !ok {
+		conn, err := openEchoConn(v6, opts)
+		if err != nil {
+			return nil, err
+		}
+		sock = &sharedSocket{
+			conn:    conn,
+			proto:   icmpProtocol(dst),
+			id:      rand.Intn(1 << 16),
+			waiting: make(map[uint16]echoWaiter),
+			nextSeq: uint16(rand.Intn(1 << 16)),
+		}
+		e.sockets[key] = sock
+		go sock.receive()
+	}
+	if sock.idle != nil {
+		sock.idle.Stop()
+		sock.idle = nil
+	}
+	sock.bursts++
+
+	// Room for duplicates and ICMP errors, answers that do not fit are dropped
+	return &sharedBurst{engine: e, key: key, sock: sock, dst: dst, answers: make(chan echoAnswer, 2*count+1)}, nil
+}
+
+// release ends the use of sock by a burst and closes sock once it was idle for idleSocketTimeout
+func (e *echoEngine) release(key string, sock *sharedSocket) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sock.bursts--
+	if sock.bursts > 0 {
+		return
+	}
+	sock.idle = time.AfterFunc(idleSocketTimeout, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		// A burst may have taken the socket while the timer fired
+		if e.sockets[key] == sock && sock.bursts == 0 {
+			sock.conn.Close()
+			delete(e.sockets, key)
+		}
+	})
+}
+
+// Close closes all sockets, their receive loops end with them
+func (e *echoEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for key, sock := range e.sockets {
+		if sock.idle != nil {
+			sock.idle.Stop()
+		}
+		sock.conn.Close()
+		delete(e.sockets, key)
+	}
+	return nil
+}
+
+// receive reads the answers of the socket until it is closed. A socket that keeps
+// failing, e.g. because its network went down, is retried with a growing delay.
+func (s *sharedSocket) receive() {
+	buf := make([]byte, 1<<16)
+	var backoff time.Duration
+	for {
+		n, ttl, peer, err := s.conn.readPacket(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if backoff == 0 {
+				fmt.Printf("Error reading from ICMP socket: %v\n", err)
+				backoff = minReceiveBackoff
+			} else {
+				backoff = min(2*backoff, maxReceiveBackoff)
+			}
+			time.Sleep(backoff)
+			continue
+		}
+		backoff = 0
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(s.proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		id, seq, ok := echoReference(msg)
+		if !ok || (s.conn.privileged && id != s.id) {
+			continue
+		}
+
+		s.mu.Lock()
+		w, ok := s.waiting[uint16(seq)]
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		// ICMP errors come from a router on the way, echo replies only from the target
+		from := peerIP(peer)
+		if _, isReply := msg.Body.(*icmp.Echo); isReply && !from.Equal(w.burst.dst) {
+			continue
+		}
+
+		select {
+		case w.burst.answers <- echoAnswer{seq: w.seq, msg: msg, from: from, received: received, ttl: ttl}:
+		default:
+		}
+	}
+}
+
+// register reserves a free sequence number on the wire for the request seq of burst
+func (s *sharedSocket) register(burst *sharedBurst, seq int) (uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for range 1 << 16 {
+		wire := s.nextSeq
+		s.nextSeq++
+		if _, used := s.waiting[wire]; !used {
+			s.waiting[wire] = echoWaiter{burst: burst, seq: seq}
+			return wire, nil
+		}
+	}
+	return 0, errors.New("all ICMP sequence numbers are in use")
+}
+
+func (b *sharedBurst) send(_, seq int, payload []byte) error {
+	wire, err := b.sock.register(b, seq)
+	if err != nil {
+		return err
+	}
+	b.wire = append(b.wire, wire)
+
+	return b.sock.conn.sendEcho(b.dst, b.sock.id, int(wire), payload)
+}
+
+func (b *sharedBurst) read(ctx context.Context, _ []byte, _ int, deadline time.Time) (echoAnswer, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case answer := <-b.answers:
+		return answer, nil
+	case <-timer.C:
+		return echoAnswer{}, os.ErrDeadlineExceeded
+	case <-ctx.Done():
+		return echoAnswer{}, ctx.Err()
+	}
+}
+
+// Close releases the sequence numbers of the burst and its use of the socket
+func (b *sharedBurst) Close() error {
+	b.sock.mu.Lock()
+	for _, wire := range b.wire {
+		delete(b.sock.waiting, wire)
+	}
+	b.sock.mu.Unlock()
+
+	b.engine.release(b.key, b.sock)
+	return nil
+}
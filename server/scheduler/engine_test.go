@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"context"
+	"lagident/model"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEchoEngine_Loopback(t *testing.T) {
+	engine := newEchoEngine()
+	defer engine.Close()
+
+	first, err := engine.burst(net.ParseIP("127.0.0.1"), socketOptions{}, 1)
+	if err != nil {
+		t.Skipf("cannot open an ICMP socket: %v", err)
+	}
+	first.Close()
+
+	// Many targets probe at the same time over the same socket,
+	// every burst must only get the replies to its own requests.
+	results := make([]Result, 20)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			target := &model.Target{Address: "127.0.0.1", Count: 3, Spacing: 10, PayloadSize: 16 + i}
+			results[i] = ICMPProber{Engine: engine}.Probe(context.Background(), target, time.Second)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if result.Lost {
+			t.Fatalf("probe %d was lost: %v", i, result.Err)
+		}
+		if result.Sent != 3 || len(result.Rtts) != 3 {
+			t.Errorf("probe %d sent %d, got %d replies, want 3 and 3", i, result.Sent, len(result.Rtts))
+		}
+		if result.Duplicates != 0 || result.Corrupted != 0 {
+			t.Errorf("probe %d got %d duplicates and %d corrupted replies, want none", i, result.Duplicates, result.Corrupted)
+		}
+	}
+
+	if len(engine.sockets) != 1 {
+		t.Errorf("engine opened %d sockets, want 1", len(engine.sockets))
+	}
+	for _, sock := range engine.sockets {
+		if len(sock.waiting) != 0 {
+			t.Errorf("%d sequence numbers are still in use after all bursts were closed", len(sock.waiting))
+		}
+		if sock.bursts != 0 || sock.idle == nil {
+			t.Errorf("socket is used by %d bursts after all bursts were closed, want 0 and an idle timer", sock.bursts)
+		}
+	}
+}
+
+func TestSharedBurst_ReadCanceled(t *testing.T) {
+	burst := &sharedBurst{answers: make(chan echoAnswer)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Deleting a target must not wait for the timeout of its burst
+	start := time.Now()
+	_, err := burst.read(ctx, nil, 0, start.Add(time.Minute))
+	if err != context.Canceled {
+		t.Errorf("read() = %v, want %v", err, context.Canceled)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("read() took %v after ctx was canceled", time.Since(start))
+	}
+}
+
+func TestSharedSocket_Register(t *testing.T) {
+	sock := &sharedSocket{waiting: make(map[uint16]echoWaiter), nextSeq: 1<<16 - 1}
+	burst := &sharedBurst{sock: sock}
+
+	// The sequence numbers wrap around and skip the ones in use
+	first, _ := sock.register(burst, 0)
+	second, _ := sock.register(burst, 1)
+	if first != 1<<16-1 || second != 0 {
+		t.Errorf("register() = %d and %d, want 65535 and 0", first, second)
+	}
+
+	sock.nextSeq = first
+	third, _ := sock.register(burst, 2)
+	if third != 1 {
+		t.Errorf("register() = %d, want 1 as 65535 and 0 are in use", third)
+	}
+
+	for seq := 3; seq < 1<<16; seq++ {
+		if _, err := sock.register(burst, seq); err != nil {
+			t.Fatalf("register() failed with %d sequence numbers in use: %v", seq, err)
+		}
+	}
+	if _, err := sock.register(burst, 1<<16); err == nil {
+		t.Error("register() should fail when all sequence numbers are in use")
+	}
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"lagident/model"
+	"net"
+	"sync"
+	"time"
+)
+
+// loadFamilyStats returns the statistics of every address family of a dual-stack target,
+// empty ones for the families that have none yet
+func (s *Scheduler) loadFamilyStats(target *model.Target) map[string]*model.FamilyStats {
+	families := make(map[string]*model.FamilyStats, len(model.Families))
+	for _, family := range model.Families {
+		families[family] = s.store.family(target.Uuid, family)
+		if families[family] == nil {
+			families[family] = &model.FamilyStats{TargetUuid: target.Uuid, Family: family}
+		}
+	}
+	return families
+}
+
+// probeDualStack probes the IPv4 and the IPv6 address of target at the same time
+// and saves the result of each family. It returns the result a client with happy
+// eyeballs would see: the faster reply, lost only if both families were lost.
+func (s *Scheduler) probeDualStack(ctx context.Context, target *model.Target, timeout time.Duration, factors Factors, families map[string]*model.FamilyStats) Result {
+	addresses, err := resolveFamilies(ctx, targetHost(target), target.Netns)
+
+	results := make([]Result, len(model.Families))
+	var wg sync.WaitGroup
+	for i, family := range model.Families {
+		ip := addresses[family]
+		if ip == nil {
+			results[i] = Result{Lost: true, Reason: model.ReasonResolve, Err: err}
+			if err == nil {
+				results[i].Err = fmt.Errorf("%s has no %s address", target.Address, family)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, ip net.IP) {
+			defer wg.Done()
+			single := *target
+			single.Address = ip.String()
+			results[i] = s.proberFor(&single).Probe(ctx, &single, timeout)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return Result{Lost: true, Err: ctx.Err()}
+	}
+
+	timestamp := time.Now().Unix()
+	for i, family := range model.Families {
+		s.saveFamilyResult(target, families[family], addresses[family], results[i], factors, timestamp)
+	}
+
+	return fastest(results)
+}
+
+// resolveFamilies returns the first IPv4 and the first IPv6 address of host by family,
+// resolved in the network namespace netns
+func resolveFamilies(ctx context.Context, host, netns string) (map[string]net.IP, error) {
+	ips, err := resolverFor(netns).LookupIPAddr(ctx, host)
+
+	addresses := make(map[string]net.IP, len(model.Families))
+	for _, ip := range ips {
+		family := model.FamilyIPv6
+		if ip.IP.To4() != nil {
+			family = model.FamilyIPv4
+		}
+		if addresses[family] == nil {
+			addresses[family] = ip.IP
+		}
+	}
+	return addresses, err
+}
+
+// fastest returns the reply with the lowest latency, or the first result if all were lost
+func fastest(results []Result) Result {
+	var best *Result
+	for i := range results {
+		if results[i].Lost {
+			continue
+		}
+		if best == nil || results[i].Latency < best.Latency {
+			best = &results[i]
+		}
+	}
+	if best == nil {
+		return results[0]
+	}
+	return *best
+}
+
+// saveFamilyResult stores the result of one address family and updates its statistics
+func (s *Scheduler) saveFamilyResult(target *model.Target, stats *model.FamilyStats, ip net.IP, result Result, factors Factors, timestamp int64) {
+	address := ""
+	if ip != nil {
+		address = ip.String()
+	}
+
+	familyResult := model.FamilyResult{
+		TargetUuid: target.Uuid,
+		Timestamp:  timestamp,
+		Family:     stats.Family,
+		Address:    address,
+		Lost:       result.Lost,
+
+		ProbeSettings: target.Settings(),
+	}
+	if result.Lost {
+		familyResult.Reason = result.reason()
+	} else {
+		familyResult.Latency = result.Latency
+	}
+
+	s.store.addFamilyResult(familyResult)
+
+	if address != "" {
+		s.saveResolution(target, stats.Family, stats.Address, address, timestamp)
+		stats.Address = address
+	}
+
+	s.updateFamilyStats(stats, result, factors)
+	stats.Timestamp = timestamp
+
+	s.store.putFamily(*stats)
+}
+
+// updateFamilyStats adds a result to the statistics of an address family
+func (s *Scheduler) updateFamilyStats(stats *model.FamilyStats, result Result, factors Factors) {
+	sent, recv := result.packets()
+	loss := float64(sent-recv) / float64(sent) * 100
+	stats.Loss15m = s.expAvg(stats.Loss15m, loss, factors.Fac15m)
+	stats.Loss6h = s.expAvg(stats.Loss6h, loss, factors.Fac6h)
+	stats.Loss24h = s.expAvg(stats.Loss24h, loss, factors.Fac24h)
+
+	if !result.Lost {
+		if stats.Recv == 0 || result.Latency < stats.Min {
+			stats.Min = result.Latency
+		}
+		stats.Max = max(stats.Max, result.Latency)
+		stats.Last = result.Latency
+		stats.Avg15m = s.expAvg(stats.Avg15m, result.Latency, factors.Fac15m)
+		stats.Avg6h = s.expAvg(stats.Avg6h, result.Latency, factors.Fac6h)
+		stats.Avg24h = s.expAvg(stats.Avg24h, result.Latency, factors.Fac24h)
+	}
+
+	stats.Sent += uint64(sent)
+	stats.Recv += uint64(recv)
+}
@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"lagident/model"
+	"testing"
+)
+
+func TestFastest(t *testing.T) {
+	v4 := Result{Latency: 12}
+	v6 := Result{Latency: 9}
+	lost := Result{Lost: true, Reason: model.ReasonTimeout}
+
+	if got := fastest([]Result{v4, v6}); got.Latency != 9 {
+		t.Errorf("fastest = %v ms, want 9 ms", got.Latency)
+	}
+	if got := fastest([]Result{lost, v4}); got.Lost || got.Latency != 12 {
+		t.Errorf("fastest = %+v, want the IPv4 reply", got)
+	}
+	if got := fastest([]Result{lost, {Lost: true, Reason: model.ReasonResolve}}); got.Reason != model.ReasonTimeout {
+		t.Errorf("fastest = %+v, want the first lost result", got)
+	}
+}
+
+func TestResolveFamilies(t *testing.T) {
+	addresses, err := resolveFamilies(context.Background(), "localhost", "")
+	if err != nil {
+		t.Skipf("cannot resolve localhost: %v", err)
+	}
+	if ip := addresses[model.FamilyIPv4]; ip != nil && ip.To4() == nil {
+		t.Errorf("IPv4 address %v is not an IPv4 address", ip)
+	}
+	if ip := addresses[model.FamilyIPv6]; ip != nil && ip.To4() != nil {
+		t.Errorf("IPv6 address %v is not an IPv6 address", ip)
+	}
+
+	addresses, err = resolveFamilies(context.Background(), "::1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addresses[model.FamilyIPv4] != nil || addresses[model.FamilyIPv6] == nil {
+		t.Errorf("resolveFamilies(::1) = %v, want only an IPv6 address", addresses)
+	}
+}
+
+func TestUpdateFamilyStats(t *testing.T) {
+	s := &Scheduler{}
+	factors := Factors{Fac15m: 0.5, Fac6h: 0.5, Fac24h: 0.5}
+	stats := &model.FamilyStats{Family: model.FamilyIPv6}
+
+	s.updateFamilyStats(stats, Result{Latency: 20, Sent: 2, Rtts: []float64{20}}, factors)
+	s.updateFamilyStats(stats, Result{Latency: 10}, factors)
+	s.updateFamilyStats(stats, Result{Lost: true}, factors)
+
+	if stats.Sent != 4 || stats.Recv != 2 {
+		t.Errorf("sent/recv = %d/%d, want 4/2", stats.Sent, stats.Recv)
+	}
+	if stats.Min != 10 || stats.Max != 20 || stats.Last != 10 {
+		t.Errorf("min/max/last = %v/%v/%v, want 10/20/10", stats.Min, stats.Max, stats.Last)
+	}
+	// 50% loss, no loss, 100% loss
+	if stats.Loss15m != 56.25 || stats.Loss6h != 56.25 {
+		t.Errorf("loss15m/loss6h = %v/%v, want 56.25/56.25", stats.Loss15m, stats.Loss6h)
+	}
+}
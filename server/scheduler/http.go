@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"lagident/model"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// HTTPProber sends a GET request to the URL in target.Address and records
+// how long name resolution, the TCP handshake, the TLS handshake and the
+// server (time to first byte) took.
+type HTTPProber struct {
+	// TLSClientConfig is used for https targets, nil uses the system defaults
+	TLSClientConfig *tls.Config
+}
+
+func (p HTTPProber) Probe(ctx context.Context, target *model.Target, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, wroteRequest, firstByte time.Time
+	// address is the IP address of the server, or of the proxy if one is used
+	var address string
+
+	// With happy eyeballs the connects run in parallel dial goroutines, only the
+	// first connect that succeeds counts
+	var connectMu sync.Mutex
+	connectStarts := make(map[string]time.Time)
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectStart: func(_, addr string) {
+			connectMu.Lock()
+			defer connectMu.Unlock()
+			connectStarts[addr] = time.Now()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			connectMu.Lock()
+			defer connectMu.Unlock()
+			if err == nil && connectDone.IsZero() {
+				connectStart, connectDone = connectStarts[addr], time.Now()
+			}
+		},
+		GotConn:              func(info httptrace.GotConnInfo) { address = remoteIP(info.Conn.RemoteAddr()) },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target.Address, nil)
+	if err != nil {
+		return Result{Lost: true, Err: err}
+	}
+
+	// Every probe has to open a new connection, otherwise we would only measure
+	// DNS, connect and TLS on the first probe.
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DialContext:       newDialer(target, "tcp").DialContext,
+			DisableKeepAlives: true,
+			TLSClientConfig:   p.TLSClientConfig,
+		},
+		// Redirects would mix the timings of multiple requests
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Lost: true, Err: err, Address: address}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return Result{Lost: true, Reason: model.ReasonStatus, Err: fmt.Errorf("server responded with %s", resp.Status), Address: address}
+	}
+
+	connectMu.Lock()
+	connect := span(connectStart, connectDone)
+	connectMu.Unlock()
+
+	return Result{
+		Latency: milliseconds(firstByte.Sub(start)),
+		Address: address,
+		HTTPTiming: &model.HTTPTiming{
+			DNS:     milliseconds(span(dnsStart, dnsDone)),
+			Connect: milliseconds(connect),
+			TLS:     milliseconds(span(tlsStart, tlsDone)),
+			TTFB:    milliseconds(span(wroteRequest, firstByte)),
+		},
+	}
+}
+
+// span returns the duration between start and end or 0 if one of them did not happen
+func span(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// milliseconds converts d to milliseconds with floating point precision
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"lagident/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPProber_Probe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	target := &model.Target{Address: server.URL, Probe: model.ProbeHTTP}
+
+	result := HTTPProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe was lost: %v", result.Err)
+	}
+	if result.HTTPTiming == nil {
+		t.Fatal("http timing is missing")
+	}
+	if result.HTTPTiming.Connect <= 0 {
+		t.Errorf("connect phase should be greater than 0, got %v", result.HTTPTiming.Connect)
+	}
+	if result.HTTPTiming.TLS != 0 {
+		t.Errorf("plain http should not have a tls phase, got %v", result.HTTPTiming.TLS)
+	}
+	if result.HTTPTiming.TTFB < 20 {
+		t.Errorf("ttfb should include the server delay, got %v", result.HTTPTiming.TTFB)
+	}
+	if result.Latency < result.HTTPTiming.TTFB {
+		t.Errorf("latency %v should not be less than ttfb %v", result.Latency, result.HTTPTiming.TTFB)
+	}
+	if result.Address != "127.0.0.1" {
+		t.Errorf("address = %q, want 127.0.0.1", result.Address)
+	}
+}
+
+func TestHTTPProber_Probe_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	target := &model.Target{Address: server.URL, Probe: model.ProbeHTTP}
+	prober := HTTPProber{
+		TLSClientConfig: server.Client().Transport.(*http.Transport).TLSClientConfig,
+	}
+
+	result := prober.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe was lost: %v", result.Err)
+	}
+	if result.HTTPTiming.TLS <= 0 {
+		t.Errorf("tls phase should be greater than 0, got %v", result.HTTPTiming.TLS)
+	}
+}
+
+func TestHTTPProber_Probe_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	target := &model.Target{Address: server.URL, Probe: model.ProbeHTTP}
+
+	result := HTTPProber{}.Probe(context.Background(), target, time.Second)
+	if !result.Lost {
+		t.Errorf("probe with status 502 should be lost")
+	}
+}
@@ -0,0 +1,265 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"lagident/model"
+	"math/rand"
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// ICMPProber sends a burst of target.Count ICMP echo requests to the target,
+// target.Spacing apart.
+type ICMPProber struct {
+	// Grace is the time to wait for late replies after the timeout
+	Grace time.Duration
+	// Engine shares its sockets with the probes of other targets.
+	// Without an engine every probe opens a socket of its own.
+	Engine *echoEngine
+}
+
+func (p ICMPProber) Probe(ctx context.Context, target *model.Target, timeout time.Duration) Result {
+	opts := sourceOptions(target)
+	dst, err := resolveTarget(ctx, target.Address, opts)
+	if err != nil {
+		return Result{Lost: true, Reason: model.ReasonResolve, Err: err}
+	}
+
+	// DSCP is the upper six bits of the TOS byte or traffic class
+	opts.tos = target.DSCP << 2
+	opts.dontFragment = target.DontFragment
+
+	result := p.burst(ctx, target, dst, opts, timeout)
+	result.Address = dst.String()
+	return result
+}
+
+// burst sends the echo requests of a burst to dst and collects the replies
+func (p ICMPProber) burst(ctx context.Context, target *model.Target, dst net.IP, opts socketOptions, timeout time.Duration) Result {
+	count := target.BurstCount()
+	sock, err := p.open(dst, opts, count)
+	if err != nil {
+		return Result{Lost: true, Reason: model.ReasonSend, Err: err}
+	}
+	defer sock.Close()
+
+	spacing := target.SpacingDuration()
+	size := target.PayloadBytes()
+	id := rand.Intn(1 << 16)
+	buf := make([]byte, max(1500, size+64))
+	burst := newBurstReplies(count, timeout, size)
+
+	// The timeout applies to every echo request, so the last one gets as much time as the first one.
+	// Replies that arrive during the grace period after it are late.
+	start := time.Now()
+	deadline := start.Add(time.Duration(count-1)*spacing + timeout + p.Grace)
+
+	for {
+		if ctx.Err() != nil {
+			return Result{Lost: true, Sent: len(burst.sentAt), Err: ctx.Err()}
+		}
+
+		now := time.Now()
+		nextSend := start.Add(time.Duration(len(burst.sentAt)) * spacing)
+		if len(burst.sentAt) < count && !now.Before(nextSend) {
+			seq := len(burst.sentAt)
+			burst.sent(seq, now, sock.send(id, seq, echoPayload(seq, size)))
+			continue
+		}
+
+		if len(burst.sentAt) == count && (burst.pending == 0 || !now.Before(deadline)) {
+			break
+		}
+
+		wait := deadline
+		if len(burst.sentAt) < count {
+			wait = nextSend
+		}
+
+		answer, err := sock.read(ctx, buf, id, wait)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return Result{Lost: true, Sent: len(burst.sentAt), Err: err}
+		}
+		burst.add(answer)
+	}
+
+	return burst.result()
+}
+
+// open returns the socket for a burst of count echo requests to dst
+func (p ICMPProber) open(dst net.IP, opts socketOptions, count int) (echoTransport, error) {
+	if p.Engine == nil {
+		return openEchoSocket(dst, opts)
+	}
+	return p.Engine.burst(dst, opts, count)
+}
+
+// echoPayload returns the payload of the echo request with seq. The pattern
+// depends on seq, so corrupted replies and replies with swapped payloads stand out.
+func echoPayload(seq, size int) []byte {
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(seq + i)
+	}
+	return payload
+}
+
+// hopCount infers the number of hops to a host from the TTL of its reply.
+// Hosts start with a TTL of 64 (Linux, macOS), 128 (Windows) or 255 (routers)
+// and every router on the way decrements it. Returns 0 if the TTL is unknown.
+func hopCount(ttl int) int {
+	if ttl <= 0 {
+		return 0
+	}
+	for _, initial := range []int{32, 64, 128, 255} {
+		if ttl <= initial {
+			return initial - ttl
+		}
+	}
+	return 0
+}
+
+// burstReplies keeps track of the answers to the echo requests of a burst
+type burstReplies struct {
+	timeout time.Duration
+	size    int
+	// sentAt holds the send time of every echo request by sequence number
+	sentAt []time.Time
+	// answered is true for requests that need no more waiting,
+	// replied for requests that got an echo reply.
+	answered []bool
+	replied  []bool
+	pending  int
+	// highest is the sequence number of the latest request that got a reply
+	highest int
+
+	rtts    []float64
+	seqs    []int
+	ttl     int
+	late    []model.LateReply
+	icmpErr *icmp.Message
+	sendErr error
+
+	duplicates int
+	reordered  int
+	corrupted  int
+}
+
+func newBurstReplies(count int, timeout time.Duration, size int) *burstReplies {
+	return &burstReplies{
+		timeout:  timeout,
+		size:     size,
+		sentAt:   make([]time.Time, 0, count),
+		answered: make([]bool, count),
+		replied:  make([]bool, count),
+		highest:  -1,
+	}
+}
+
+// sent records an echo request, err is the error of sending it
+func (b *burstReplies) sent(seq int, at time.Time, err error) {
+	b.sentAt = append(b.sentAt, at)
+	if err != nil {
+		b.sendErr = err
+		b.answered[seq] = true
+		return
+	}
+	b.pending++
+}
+
+// add records an echo reply or ICMP error
+func (b *burstReplies) add(answer echoAnswer) {
+	if answer.seq >= len(b.sentAt) {
+		return
+	}
+
+	echo, isReply := answer.msg.Body.(*icmp.Echo)
+	if isReply {
+		if b.replied[answer.seq] {
+			b.duplicates++
+			return
+		}
+		b.replied[answer.seq] = true
+
+		if answer.seq < b.highest {
+			b.reordered++
+		}
+		b.highest = max(b.highest, answer.seq)
+
+		if !bytes.Equal(echo.Data, echoPayload(answer.seq, b.size)) {
+			b.corrupted++
+		}
+	}
+
+	if b.answered[answer.seq] {
+		return
+	}
+	b.answered[answer.seq] = true
+	b.pending--
+
+	rtt := answer.received.Sub(b.sentAt[answer.seq])
+	switch {
+	case isReply && rtt > b.timeout:
+		b.late = append(b.late, model.LateReply{Seq: answer.seq, Latency: milliseconds(rtt)})
+		b.ttl = answer.ttl
+	case isReply:
+		b.rtts = append(b.rtts, milliseconds(rtt))
+		b.seqs = append(b.seqs, answer.seq)
+		b.ttl = answer.ttl
+	case rtt <= b.timeout:
+		b.icmpErr = answer.msg
+	}
+}
+
+func (b *burstReplies) result() Result {
+	result := Result{
+		Sent:       len(b.sentAt),
+		Rtts:       b.rtts,
+		RttSeqs:    b.seqs,
+		TTL:        b.ttl,
+		Late:       b.late,
+		Duplicates: b.duplicates,
+		Reordered:  b.reordered,
+		Corrupted:  b.corrupted,
+	}
+
+	// A late reply is a reply, a burst that only got late ones is slow but not lost
+	if len(b.rtts) > 0 || len(b.late) > 0 {
+		_, result.Latency, _ = burstStats(result.samples())
+		return result
+	}
+
+	result.Lost = true
+	result.Reason = model.ReasonTimeout
+	result.Err = fmt.Errorf("no echo reply within %v", b.timeout)
+	switch {
+	case b.icmpErr != nil:
+		result.ICMPType, result.ICMPCode = icmpTypeCode(b.icmpErr)
+		result.Reason = icmpReason(b.icmpErr)
+		result.Err = fmt.Errorf("received ICMP type %d code %d", result.ICMPType, result.ICMPCode)
+	case b.sendErr != nil:
+		result.Reason = model.ReasonSend
+		result.Err = b.sendErr
+	}
+	return result
+}
+
+// icmpReason returns the loss reason for an ICMP error message
+func icmpReason(msg *icmp.Message) string {
+	switch msg.Body.(type) {
+	case *icmp.TimeExceeded:
+		return model.ReasonTimeExceeded
+	case *icmp.DstUnreach, *icmp.PacketTooBig:
+		return model.ReasonUnreachable
+	}
+	return model.ReasonError
+}
@@ -0,0 +1,258 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lagident/model"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+func TestICMPProber_Loopback(t *testing.T) {
+	sock, err := openEchoSocket(net.ParseIP("127.0.0.1"), socketOptions{})
+	if err != nil {
+		t.Skipf("cannot open an ICMP socket: %v", err)
+	}
+	sock.Close()
+
+	target := &model.Target{Address: "127.0.0.1", Count: 3, Spacing: 10}
+	result := ICMPProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe to loopback was lost: %v", result.Err)
+	}
+	if result.Sent != 3 || len(result.Rtts) != 3 {
+		t.Errorf("sent %d, got %d replies, want 3 and 3", result.Sent, len(result.Rtts))
+	}
+}
+
+func TestICMPProber_Settings(t *testing.T) {
+	opts := socketOptions{tos: 46 << 2, dontFragment: true}
+	sock, err := openEchoSocket(net.ParseIP("127.0.0.1"), opts)
+	if err != nil {
+		t.Skipf("cannot open an ICMP socket with options: %v", err)
+	}
+	sock.Close()
+
+	// Loopback has a large MTU, so a large payload fits even without fragmentation
+	target := &model.Target{Address: "127.0.0.1", PayloadSize: 4000, DSCP: 46, DontFragment: true}
+	result := ICMPProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe to loopback was lost: %v", result.Err)
+	}
+	if result.Corrupted != 0 {
+		t.Errorf("corrupted = %d, want 0", result.Corrupted)
+	}
+}
+
+func TestICMPProber_Interface(t *testing.T) {
+	opts := socketOptions{device: "lo"}
+	sock, err := openEchoSocket(net.ParseIP("127.0.0.1"), opts)
+	if err != nil {
+		t.Skipf("cannot bind an ICMP socket to the loopback interface: %v", err)
+	}
+	sock.Close()
+
+	target := &model.Target{Address: "127.0.0.1", Interface: "lo", SourceAddress: "127.0.0.1"}
+	result := ICMPProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe to loopback was lost: %v", result.Err)
+	}
+
+	// Loopback can not be reached through another interface
+	target.Interface = "lagident0"
+	result = ICMPProber{}.Probe(context.Background(), target, time.Second)
+	if !result.Lost || result.Reason != model.ReasonSend {
+		t.Errorf("result = %+v, want lost as send for a missing interface", result)
+	}
+}
+
+func TestICMPProber_Resolve(t *testing.T) {
+	target := &model.Target{Address: "lagident.invalid"}
+	result := ICMPProber{}.Probe(context.Background(), target, time.Second)
+	if !result.Lost || result.reason() != model.ReasonResolve {
+		t.Errorf("lost = %v, reason = %q, want true and %q", result.Lost, result.reason(), model.ReasonResolve)
+	}
+}
+
+func TestLossReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, model.ReasonTimeout},
+		{&net.DNSError{Err: "no such host", Name: "example.invalid"}, model.ReasonResolve},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, model.ReasonRefused},
+		{&net.OpError{Op: "dial", Err: syscall.EHOSTUNREACH}, model.ReasonUnreachable},
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), model.ReasonTimeout},
+		{&net.OpError{Op: "write", Err: syscall.EPERM}, model.ReasonSend},
+		{errors.New("something else"), model.ReasonError},
+	}
+
+	for _, test := range tests {
+		if got := lossReason(test.err); got != test.want {
+			t.Errorf("lossReason(%v) = %q, want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestICMPReason(t *testing.T) {
+	msg := &icmp.Message{Type: ipv4.ICMPTypeDestinationUnreachable, Code: 3, Body: &icmp.DstUnreach{}}
+	if got := icmpReason(msg); got != model.ReasonUnreachable {
+		t.Errorf("icmpReason() = %q, want %q", got, model.ReasonUnreachable)
+	}
+	if typ, code := icmpTypeCode(msg); typ != 3 || code != 3 {
+		t.Errorf("icmpTypeCode() = %d, %d, want 3, 3", typ, code)
+	}
+
+	msg = &icmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &icmp.TimeExceeded{}}
+	if got := icmpReason(msg); got != model.ReasonTimeExceeded {
+		t.Errorf("icmpReason() = %q, want %q", got, model.ReasonTimeExceeded)
+	}
+}
+
+func TestLateGrace(t *testing.T) {
+	tests := []struct {
+		target model.Target
+		want   time.Duration
+	}{
+		// 15s interval, 10s timeout: 5s left until the next probe
+		{model.Target{}, 5 * time.Second},
+		// Never longer than the timeout
+		{model.Target{Interval: 60, Timeout: 1000}, time.Second},
+		// No time left after the burst
+		{model.Target{Interval: 1, Timeout: 1000, Count: 5, Spacing: 100}, 0},
+	}
+
+	for _, test := range tests {
+		if got := lateGrace(&test.target); got != test.want {
+			t.Errorf("lateGrace(%+v) = %v, want %v", test.target, got, test.want)
+		}
+	}
+}
+
+func TestResultPackets_Late(t *testing.T) {
+	result := Result{Latency: 1200, Sent: 3, Late: []model.LateReply{{Seq: 1, Latency: 1200}}}
+
+	// A late reply is not a lost packet
+	sent, recv := result.packets()
+	if sent != 3 || recv != 1 {
+		t.Errorf("packets() = %d, %d, want 3, 1", sent, recv)
+	}
+	if samples := result.samples(); len(samples) != 1 || samples[0] != 1200 {
+		t.Errorf("samples() = %v, want the late reply as latency sample", samples)
+	}
+}
+
+func TestResultSamples_Order(t *testing.T) {
+	// The reply to request 2 was on time, the one to request 1 was late
+	result := Result{Sent: 3, Rtts: []float64{10, 12}, RttSeqs: []int{0, 2}, Late: []model.LateReply{{Seq: 1, Latency: 1200}}}
+
+	samples := result.samples()
+	if len(samples) != 3 || samples[0] != 10 || samples[1] != 1200 || samples[2] != 12 {
+		t.Errorf("samples() = %v, want [10 1200 12]", samples)
+	}
+}
+
+func TestBurstReplies_OnlyLate(t *testing.T) {
+	start := time.Now()
+	burst := newBurstReplies(2, time.Second, model.DefaultPayloadSize)
+	burst.sent(0, start, nil)
+	burst.sent(1, start, nil)
+	burst.add(echoAnswer{
+		seq:      1,
+		msg:      &icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{Seq: 1, Data: echoPayload(1, model.DefaultPayloadSize)}},
+		received: start.Add(1500 * time.Millisecond),
+	})
+
+	// Slow, but not lost
+	result := burst.result()
+	if result.Lost || result.Latency != 1500 {
+		t.Errorf("result() = %+v, want a latency of 1500 ms and no loss", result)
+	}
+}
+
+func TestBurstReplies(t *testing.T) {
+	start := time.Now()
+	burst := newBurstReplies(4, time.Second, model.DefaultPayloadSize)
+	for seq := 0; seq < 4; seq++ {
+		burst.sent(seq, start, nil)
+	}
+
+	reply := func(seq int, payload []byte, after time.Duration) echoAnswer {
+		return echoAnswer{
+			seq:      seq,
+			msg:      &icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{Seq: seq, Data: payload}},
+			received: start.Add(after),
+			ttl:      57,
+		}
+	}
+
+	burst.add(reply(1, echoPayload(1, model.DefaultPayloadSize), 10*time.Millisecond))
+	// Duplicate of the first reply
+	burst.add(reply(1, echoPayload(1, model.DefaultPayloadSize), 11*time.Millisecond))
+	// Arrives after the reply to a later request
+	burst.add(reply(0, echoPayload(0, model.DefaultPayloadSize), 12*time.Millisecond))
+	// Payload of another request
+	burst.add(reply(2, echoPayload(3, model.DefaultPayloadSize), 13*time.Millisecond))
+	// After the timeout
+	burst.add(reply(3, echoPayload(3, model.DefaultPayloadSize), 1500*time.Millisecond))
+
+	result := burst.result()
+	if result.Lost {
+		t.Fatal("burst with replies should not be lost")
+	}
+	if result.Duplicates != 1 || result.Reordered != 1 || result.Corrupted != 1 {
+		t.Errorf("duplicates, reordered, corrupted = %d, %d, %d, want 1, 1, 1", result.Duplicates, result.Reordered, result.Corrupted)
+	}
+	if len(result.Rtts) != 3 || len(result.Late) != 1 || result.Late[0].Seq != 3 {
+		t.Errorf("rtts = %v, late = %v, want 3 rtts and seq 3 late", result.Rtts, result.Late)
+	}
+	// 10, 12 and 13 ms in time and 1500 ms late
+	if result.Latency != 383.75 {
+		t.Errorf("latency = %v, want the average of all replies 383.75", result.Latency)
+	}
+	if result.TTL != 57 {
+		t.Errorf("ttl = %d, want 57", result.TTL)
+	}
+	if burst.pending != 0 {
+		t.Errorf("pending = %d, want 0", burst.pending)
+	}
+}
+
+func TestHopCount(t *testing.T) {
+	tests := map[int]int{
+		0:   0,
+		64:  0,
+		57:  7,
+		120: 8,
+		250: 5,
+		30:  2,
+	}
+	for ttl, want := range tests {
+		if got := hopCount(ttl); got != want {
+			t.Errorf("hopCount(%d) = %d, want %d", ttl, got, want)
+		}
+	}
+}
+
+func TestBurstReplies_Unreachable(t *testing.T) {
+	start := time.Now()
+	burst := newBurstReplies(1, time.Second, model.DefaultPayloadSize)
+	burst.sent(0, start, nil)
+	burst.add(echoAnswer{
+		seq:      0,
+		msg:      &icmp.Message{Type: ipv4.ICMPTypeDestinationUnreachable, Code: 1, Body: &icmp.DstUnreach{}},
+		received: start.Add(5 * time.Millisecond),
+	})
+
+	result := burst.result()
+	if !result.Lost || result.Reason != model.ReasonUnreachable || result.ICMPType != 3 || result.ICMPCode != 1 {
+		t.Errorf("result = %+v, want lost as unreachable with type 3 code 1", result)
+	}
+}
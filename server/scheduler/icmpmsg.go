@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"encoding/binary"
+	"net"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// icmpProtocol returns the protocol number to parse ICMP messages from ip
+func icmpProtocol(ip net.IP) int {
+	if ip.To4() == nil {
+		return protocolIPv6ICMP
+	}
+	return protocolICMP
+}
+
+// echoReference returns id and sequence of the echo request that msg answers.
+// For echo replies these are taken from the reply itself, for ICMP errors from
+// the original datagram that is quoted in the error message.
+func echoReference(msg *icmp.Message) (id, seq int, ok bool) {
+	switch body := msg.Body.(type) {
+	case *icmp.Echo:
+		if msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply {
+			return 0, 0, false
+		}
+		return body.ID, body.Seq, true
+	case *icmp.TimeExceeded:
+		return quotedEcho(body.Data)
+	case *icmp.DstUnreach:
+		return quotedEcho(body.Data)
+	case *icmp.PacketTooBig:
+		return quotedEcho(body.Data)
+	}
+	return 0, 0, false
+}
+
+// quotedEcho parses id and sequence of the echo request in the IP datagram
+// that is quoted by an ICMP error message.
+func quotedEcho(data []byte) (id, seq int, ok bool) {
+	if len(data) < 1 {
+		return 0, 0, false
+	}
+
+	var headerLen int
+	var echoRequest byte
+	switch data[0] >> 4 {
+	case 4:
+		headerLen = int(data[0]&0x0f) * 4
+		echoRequest = byte(ipv4.ICMPTypeEcho)
+	case 6:
+		headerLen = ipv6.HeaderLen
+		echoRequest = byte(ipv6.ICMPTypeEchoRequest)
+	default:
+		return 0, 0, false
+	}
+
+	// Type, code, checksum, id and sequence are the first 8 bytes of the echo request
+	if len(data) < headerLen+8 || data[headerLen] != echoRequest {
+		return 0, 0, false
+	}
+
+	echo := data[headerLen:]
+	return int(binary.BigEndian.Uint16(echo[4:6])), int(binary.BigEndian.Uint16(echo[6:8])), true
+}
+
+// newEchoRequest builds an ICMP echo request for the address family of ip
+func newEchoRequest(ip net.IP, id, seq int, payload []byte) icmp.Message {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if ip.To4() == nil {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+
+	return icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+	}
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+func TestEchoReference_TimeExceeded(t *testing.T) {
+	request := newEchoRequest(net.ParseIP("192.0.2.1"), 4711, 3, []byte("lagident"))
+	echo, err := request.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(echo),
+		TTL:      1,
+		Protocol: protocolICMP,
+		Dst:      net.ParseIP("192.0.2.1"),
+	}
+	quoted, err := header.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &icmp.Message{
+		Type: ipv4.ICMPTypeTimeExceeded,
+		Body: &icmp.TimeExceeded{Data: append(quoted, echo...)},
+	}
+
+	id, seq, ok := echoReference(msg)
+	if !ok || id != 4711 || seq != 3 {
+		t.Errorf("echoReference() = %v, %v, %v, want 4711, 3, true", id, seq, ok)
+	}
+}
+
+func TestEchoReference_IPv6DstUnreach(t *testing.T) {
+	request := newEchoRequest(net.ParseIP("2001:db8::1"), 815, 42, nil)
+	echo, err := request.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quoted := make([]byte, ipv6.HeaderLen)
+	quoted[0] = ipv6.Version << 4
+
+	msg := &icmp.Message{
+		Type: ipv6.ICMPTypeDestinationUnreachable,
+		Body: &icmp.DstUnreach{Data: append(quoted, echo...)},
+	}
+
+	id, seq, ok := echoReference(msg)
+	if !ok || id != 815 || seq != 42 {
+		t.Errorf("echoReference() = %v, %v, %v, want 815, 42, true", id, seq, ok)
+	}
+}
+
+func TestEchoReference_EchoRequestIsNoReply(t *testing.T) {
+	msg := newEchoRequest(net.ParseIP("192.0.2.1"), 1, 1, nil)
+
+	if _, _, ok := echoReference(&msg); ok {
+		t.Errorf("an echo request must not be treated as a reply")
+	}
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import "math"
+
+// updateJitter feeds the round trip times of a burst into the interarrival
+// jitter estimator of RFC 3550 section 6.4.1:
+//
+//	J(i) = J(i-1) + (|D(i-1,i)| - J(i-1)) / 16
+//
+// Since we only know round trip times, D is the difference of two consecutive
+// round trip times. previous is the last round trip time of the burst before,
+// if hasPrevious is false the first rtt only becomes the reference.
+func updateJitter(jitter, previous float64, hasPrevious bool, rtts []float64) float64 {
+	for _, rtt := range rtts {
+		if hasPrevious {
+			jitter += (math.Abs(rtt-previous) - jitter) / 16
+		}
+		previous = rtt
+		hasPrevious = true
+	}
+	return jitter
+}
+
+// burstStats returns min, average and max of rtts
+func burstStats(rtts []float64) (min, avg, max float64) {
+	if len(rtts) == 0 {
+		return 0, 0, 0
+	}
+
+	min, max = rtts[0], rtts[0]
+	sum := 0.0
+	for _, rtt := range rtts {
+		min = math.Min(min, rtt)
+		max = math.Max(max, rtt)
+		sum += rtt
+	}
+	return min, sum / float64(len(rtts)), max
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUpdateJitter(t *testing.T) {
+	// Constant round trip times have no jitter
+	if got := updateJitter(0, 0, false, []float64{20, 20, 20, 20}); got != 0 {
+		t.Errorf("jitter of constant rtts = %v, want 0", got)
+	}
+
+	// Every difference of 16ms adds 1/16 of the difference to the estimate
+	got := updateJitter(0, 10, true, []float64{26})
+	if math.Abs(got-1) > 1e-9 {
+		t.Errorf("jitter = %v, want 1", got)
+	}
+
+	// Without a previous rtt the first sample is only used as reference
+	got = updateJitter(0, 10, false, []float64{26, 42})
+	if math.Abs(got-1) > 1e-9 {
+		t.Errorf("jitter = %v, want 1", got)
+	}
+}
+
+func TestUpdateJitter_Converges(t *testing.T) {
+	// Alternating between 10ms and 30ms is a constant difference of 20ms
+	jitter := 0.0
+	for i := 0; i < 200; i++ {
+		jitter = updateJitter(jitter, 10, i > 0, []float64{30, 10})
+	}
+
+	if math.Abs(jitter-20) > 0.01 {
+		t.Errorf("jitter = %v, want 20", jitter)
+	}
+}
+
+func TestBurstStats(t *testing.T) {
+	min, avg, max := burstStats([]float64{12, 8, 16, 4})
+	if min != 4 || avg != 10 || max != 16 {
+		t.Errorf("burstStats() = %v, %v, %v, want 4, 10, 16", min, avg, max)
+	}
+
+	min, avg, max = burstStats(nil)
+	if min != 0 || avg != 0 || max != 0 {
+		t.Errorf("burstStats(nil) = %v, %v, %v, want 0, 0, 0", min, avg, max)
+	}
+}
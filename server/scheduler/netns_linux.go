@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// netnsDir is where ip netns keeps the named network namespaces
+const netnsDir = "/var/run/netns"
+
+// inNetns runs fn on a thread that is switched into the named network namespace.
+// Sockets stay in the namespace they were created in, so only creating them has to
+// happen inside. An empty name runs fn in the namespace of Lagident.
+func inNetns(name string, fn func() error) error {
+	if name == "" {
+		return fn()
+	}
+
+	ns, err := os.Open(filepath.Join(netnsDir, name))
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+
+	// setns only switches the current thread, so the goroutine must stay on it
+	runtime.LockOSThread()
+
+	origin, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer origin.Close()
+
+	if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("cannot enter network namespace %s: %w", name, err)
+	}
+
+	fnErr := fn()
+
+	if err := unix.Setns(int(origin.Fd()), unix.CLONE_NEWNET); err != nil {
+		// Keep the thread locked, the runtime terminates it when the goroutine exits
+		return fmt.Errorf("cannot leave network namespace %s: %w", name, err)
+	}
+	runtime.UnlockOSThread()
+
+	return fnErr
+}
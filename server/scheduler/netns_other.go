@@ -0,0 +1,13 @@
+//go:build !linux
+
+package scheduler
+
+import "errors"
+
+// inNetns runs fn, network namespaces only exist on Linux
+func inNetns(name string, fn func() error) error {
+	if name != "" {
+		return errors.New("network namespaces are only supported on Linux")
+	}
+	return fn()
+}
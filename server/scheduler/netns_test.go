@@ -0,0 +1,23 @@
+package scheduler
+
+import "testing"
+
+func TestInNetns(t *testing.T) {
+	called := false
+	err := inNetns("", func() error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Errorf("inNetns without a namespace = %v, called %v, want fn to be called", err, called)
+	}
+
+	called = false
+	err = inNetns("lagident-does-not-exist", func() error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("inNetns with a missing namespace = %v, called %v, want an error", err, called)
+	}
+}
@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lagident/model"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// maxPathMTU is the largest IP packet, the discovery never reports more
+	maxPathMTU = 65535
+
+	// pmtuAttempts is the number of echo requests per packet size,
+	// a single lost request must not make the path MTU look smaller.
+	pmtuAttempts = 2
+	pmtuTimeout  = time.Second
+
+	icmpHeaderLen = 8
+)
+
+// DiscoverPathMTU returns the largest packet in bytes, including the IP header, that reaches
+// the target with the don't fragment flag set. It binary searches the size of ICMP echo requests.
+// Requires Linux, other systems can not set the flag.
+func DiscoverPathMTU(ctx context.Context, target *model.Target) (int, error) {
+	opts := sourceOptions(target)
+	opts.dontFragment = true
+
+	dst, err := resolveTarget(ctx, targetHost(target), opts)
+	if err != nil {
+		return 0, err
+	}
+
+	sock, err := openEchoSocket(dst, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer sock.Close()
+
+	// Every IPv4 link has to carry packets of 68 bytes, every IPv6 link 1280 bytes
+	p := &pmtuProbe{sock: sock, id: rand.Intn(1 << 16), header: ipv4.HeaderLen + icmpHeaderLen, buf: make([]byte, maxPathMTU)}
+	low := 68
+	if dst.To4() == nil {
+		p.header = ipv6.HeaderLen + icmpHeaderLen
+		low = 1280
+	}
+
+	passes, err := p.passes(ctx, low)
+	if err != nil {
+		return 0, err
+	}
+	if !passes {
+		return 0, fmt.Errorf("no echo reply to a packet of %d bytes", low)
+	}
+
+	return searchMTU(low, maxPathMTU, func(size int) (bool, error) {
+		return p.passes(ctx, size)
+	})
+}
+
+// searchMTU returns the largest size between low and high that passes, low has to pass.
+// Larger packets than the path MTU never pass, so a binary search is enough.
+func searchMTU(low, high int, passes func(size int) (bool, error)) (int, error) {
+	for low < high {
+		mid := low + (high-low+1)/2
+		ok, err := passes(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low, nil
+}
+
+// pmtuProbe sends the echo requests of a path MTU discovery
+type pmtuProbe struct {
+	sock   *echoSocket
+	id     int
+	seq    int
+	header int
+	buf    []byte
+}
+
+// passes returns true if an echo request of size bytes, including the IP header, got a reply
+func (p *pmtuProbe) passes(ctx context.Context, size int) (bool, error) {
+	for attempt := 0; attempt < pmtuAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		p.seq++
+		err := p.sock.send(p.id, p.seq, echoPayload(p.seq, size-p.header))
+		if errors.Is(err, syscall.EMSGSIZE) {
+			// Larger than the MTU of the local interface or a path MTU the kernel already learned
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		deadline := time.Now().Add(pmtuTimeout)
+		for {
+			answer, err := p.sock.read(ctx, p.buf, p.id, deadline)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return false, err
+			}
+			if answer.seq != p.seq {
+				continue
+			}
+
+			// Any ICMP error, usually fragmentation needed or packet too big
+			_, isReply := answer.msg.Body.(*icmp.Echo)
+			return isReply, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"context"
+	"lagident/model"
+	"testing"
+)
+
+func TestSearchMTU(t *testing.T) {
+	for _, pmtu := range []int{68, 1280, 1420, 1492, 1500, 9000, maxPathMTU} {
+		probes := 0
+		got, err := searchMTU(68, maxPathMTU, func(size int) (bool, error) {
+			probes++
+			return size <= pmtu, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != pmtu {
+			t.Errorf("searchMTU = %d, want %d", got, pmtu)
+		}
+		if probes > 16 {
+			t.Errorf("searchMTU needed %d probes for %d, want at most 16", probes, pmtu)
+		}
+	}
+}
+
+func TestDiscoverPathMTU_Loopback(t *testing.T) {
+	sock, err := openEchoSocket([]byte{127, 0, 0, 1}, socketOptions{dontFragment: true})
+	if err != nil {
+		t.Skipf("cannot open an ICMP socket with the don't fragment flag: %v", err)
+	}
+	sock.Close()
+
+	// Loopback has an MTU of 65536, so every packet passes
+	mtu, err := DiscoverPathMTU(context.Background(), &model.Target{Address: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mtu != maxPathMTU {
+		t.Errorf("path MTU = %d, want %d", mtu, maxPathMTU)
+	}
+}
@@ -0,0 +1,255 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lagident/model"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Result is the outcome of a single probe against a target.
+type Result struct {
+	// Latency in milliseconds, the average of all replies for bursts, late replies included
+	Latency float64
+	// Lost is true if the target did not answer, not even after the timeout
+	Lost bool
+	// Sent is the number of packets of a burst, 0 for probes that only send a single request
+	Sent int
+	// Rtts holds the round trip time of every reply of a burst in milliseconds,
+	// RttSeqs the sequence number of the request each of them answers
+	Rtts    []float64
+	RttSeqs []int
+	// TTL of the last echo reply, 0 for other probe kinds
+	TTL int
+	// Address is the IP address the probe was sent to, empty if the target could not be resolved
+	Address string
+	// Late holds the replies that arrived after the timeout. They are not part of Rtts,
+	// but they are replies, so they count as received and as latency like the others.
+	Late []model.LateReply
+	// Duplicates, Reordered and Corrupted count the echo replies of a burst
+	// that arrived more than once, out of order or with a different payload.
+	Duplicates int
+	Reordered  int
+	Corrupted  int
+	// Err holds the reason why a probe could not be sent or was lost
+	Err error
+	// Reason classifies why the probe was lost, see model.Reason*.
+	// Probers may leave it empty, then it is derived from Err.
+	Reason string
+	// ICMPType and ICMPCode of the ICMP error that answered an echo request instead of a reply
+	ICMPType int
+	ICMPCode int
+	// Rcode is the response code of a DNS probe, empty for other probe kinds
+	Rcode string
+	// HTTPTiming holds the phases of an HTTP probe, nil for other probe kinds
+	HTTPTiming *model.HTTPTiming
+}
+
+// packets returns the number of packets that were sent and received
+func (r Result) packets() (sent, recv int) {
+	if r.Sent == 0 {
+		if r.Lost {
+			return 1, 0
+		}
+		return 1, 1
+	}
+	return r.Sent, len(r.Rtts) + len(r.Late)
+}
+
+// reason returns why the probe was lost
+func (r Result) reason() string {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	return lossReason(r.Err)
+}
+
+// lossReason classifies the error of a lost probe
+func lossReason(err error) string {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case err == nil:
+		return model.ReasonTimeout
+	case errors.As(err, &dnsErr):
+		return model.ReasonResolve
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return model.ReasonRefused
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return model.ReasonUnreachable
+	case errors.Is(err, context.DeadlineExceeded), os.IsTimeout(err):
+		return model.ReasonTimeout
+	case errors.As(err, &opErr):
+		return model.ReasonSend
+	}
+	return model.ReasonError
+}
+
+// samples returns all round trip times of the result in milliseconds, late replies
+// included, in the order of the requests they answer
+func (r Result) samples() []float64 {
+	if r.Lost {
+		return nil
+	}
+	if r.Rtts == nil && r.Late == nil {
+		return []float64{r.Latency}
+	}
+
+	type sample struct {
+		seq int
+		rtt float64
+	}
+	ordered := make([]sample, 0, len(r.Rtts)+len(r.Late))
+	for i, rtt := range r.Rtts {
+		seq := i
+		if i < len(r.RttSeqs) {
+			seq = r.RttSeqs[i]
+		}
+		ordered = append(ordered, sample{seq, rtt})
+	}
+	for _, late := range r.Late {
+		ordered = append(ordered, sample{late.Seq, late.Latency})
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].seq < ordered[j].seq })
+
+	samples := make([]float64, len(ordered))
+	for i, s := range ordered {
+		samples[i] = s.rtt
+	}
+	return samples
+}
+
+// A Prober measures the latency to a target.
+// Implementations must return once the timeout is reached or ctx is canceled.
+type Prober interface {
+	Probe(ctx context.Context, target *model.Target, timeout time.Duration) Result
+}
+
+// proberFor returns the Prober for the probe kind of the target.
+// Targets without a probe kind are pinged.
+func (s *Scheduler) proberFor(target *model.Target) Prober {
+	switch target.Probe {
+	case model.ProbeTCP:
+		return TCPProber{}
+	case model.ProbeHTTP:
+		return HTTPProber{}
+	case model.ProbeDNS:
+		return DNSProber{}
+	default:
+		return ICMPProber{Grace: lateGrace(target), Engine: s.echo}
+	}
+}
+
+// lateGrace returns how long an ICMP probe keeps listening for late replies
+// after the timeout. As long as the timeout, but it has to end before the next probe.
+func lateGrace(target *model.Target) time.Duration {
+	burst := time.Duration(target.BurstCount()-1) * target.SpacingDuration()
+	grace := target.IntervalDuration() - burst - target.TimeoutDuration()
+	return max(0, min(grace, target.TimeoutDuration()))
+}
+
+// sourceOptions returns the socket options that bind the probes of target
+// to its source address, interface or network namespace
+func sourceOptions(target *model.Target) socketOptions {
+	return socketOptions{source: net.ParseIP(target.SourceAddress), device: target.Interface, netns: target.Netns}
+}
+
+// resolveTarget returns the IP address of host, resolved in the network namespace of opts.
+// With a source address only addresses of the same family can be reached.
+func resolveTarget(ctx context.Context, host string, opts socketOptions) (net.IP, error) {
+	ips, err := resolverFor(opts.netns).LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	source := opts.source
+	if source == nil {
+		return ips[0].IP, nil
+	}
+
+	for _, ip := range ips {
+		if (ip.IP.To4() == nil) == (source.To4() == nil) {
+			return ip.IP, nil
+		}
+	}
+	return nil, &net.DNSError{Err: fmt.Sprintf("no address of the same family as %s", source), Name: host}
+}
+
+// targetDialer dials from the source address, interface and network namespace of a target
+type targetDialer struct {
+	net.Dialer
+	opts socketOptions
+}
+
+// newDialer returns a dialer for network ("tcp" or "udp") for the probes of target
+func newDialer(target *model.Target, network string) *targetDialer {
+	opts := sourceOptions(target)
+
+	dialer := &targetDialer{opts: opts}
+	dialer.Control = func(network, _ string, c syscall.RawConn) error {
+		return controlSocket(c, strings.HasSuffix(network, "6"), opts)
+	}
+	if opts.source != nil {
+		if network == "udp" {
+			dialer.LocalAddr = &net.UDPAddr{IP: opts.source}
+		} else {
+			dialer.LocalAddr = &net.TCPAddr{IP: opts.source}
+		}
+	}
+	return dialer
+}
+
+func (d *targetDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.opts.netns == "" {
+		return d.Dialer.DialContext(ctx, network, address)
+	}
+
+	// Resolve first and dial a single address. Resolving and happy eyeballs
+	// would create sockets on other threads, outside of the namespace.
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := resolveTarget(ctx, host, d.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	err = inNetns(d.opts.netns, func() (err error) {
+		conn, err = d.Dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		return err
+	})
+	return conn, err
+}
+
+// remoteIP returns the IP address of addr without the port
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// targetHost returns the host name or IP address of the target without the
+// probe specific parts like the URL of HTTP probes or the port of DNS resolvers.
+func targetHost(target *model.Target) string {
+	switch target.Probe {
+	case model.ProbeHTTP:
+		if u, err := url.Parse(target.Address); err == nil {
+			return u.Hostname()
+		}
+	case model.ProbeDNS:
+		if host, _, err := net.SplitHostPort(target.Address); err == nil {
+			return host
+		}
+	}
+	return target.Address
+}
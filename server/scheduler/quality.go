@@ -0,0 +1,43 @@
+package scheduler
+
+import "math"
+
+// The R-factor and MOS estimation follows the simplified E-model of ITU-T G.107
+// that is commonly used by network monitoring tools. It only knows about
+// latency, jitter and loss and assumes a G.711 like codec.
+
+// rFactor estimates the transmission rating factor R (0 - 100) of a connection.
+// latency and jitter are round trip values in milliseconds, loss is in percent.
+func rFactor(latency, jitter, loss float64) float64 {
+	// Jitter buffers add delay, the fixed 10ms are for the codec
+	effectiveLatency := latency + 2*jitter + 10
+
+	var r float64
+	if effectiveLatency < 160 {
+		r = 93.2 - effectiveLatency/40
+	} else {
+		r = 93.2 - (effectiveLatency-120)/10
+	}
+
+	r -= 2.5 * loss
+
+	if r < 0 {
+		return 0
+	}
+	if r > 100 {
+		return 100
+	}
+	return r
+}
+
+// mos converts the R-factor into a mean opinion score between 1 (bad) and 4.5 (best)
+func mos(r float64) float64 {
+	if r <= 0 {
+		return 1
+	}
+	if r >= 100 {
+		return 4.5
+	}
+	// The polynomial dips slightly below 1 for very small R-factors
+	return math.Max(1, 1+0.035*r+0.000007*r*(r-60)*(100-r))
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRFactor(t *testing.T) {
+	tests := []struct {
+		name                  string
+		latency, jitter, loss float64
+		want                  float64
+	}{
+		{"perfect lan", 0, 0, 0, 92.95},
+		{"good internet", 20, 5, 0, 92.2},
+		{"long distance", 200, 10, 0, 82.2},
+		{"lossy", 20, 5, 10, 67.2},
+		{"down", 0, 0, 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rFactor(tt.latency, tt.jitter, tt.loss)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("rFactor(%v, %v, %v) = %v, want %v", tt.latency, tt.jitter, tt.loss, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMos(t *testing.T) {
+	if got := mos(0); got != 1 {
+		t.Errorf("mos(0) = %v, want 1", got)
+	}
+	if got := mos(100); got != 4.5 {
+		t.Errorf("mos(100) = %v, want 4.5", got)
+	}
+
+	// R = 93.2 is the best a G.711 call can get, which is a MOS of about 4.41
+	if got := mos(93.2); math.Abs(got-4.41) > 0.01 {
+		t.Errorf("mos(93.2) = %v, want 4.41", got)
+	}
+
+	// MOS must never decrease with a better R-factor
+	for r := 1.0; r <= 100; r++ {
+		if mos(r) < mos(r-1) {
+			t.Errorf("mos(%v) = %v is less than mos(%v) = %v", r, mos(r), r-1, mos(r-1))
+		}
+	}
+}
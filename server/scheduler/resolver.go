@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netnsEtcDir holds the configuration files of named network namespaces, ip netns exec
+// bind mounts /etc/netns/<name>/resolv.conf over /etc/resolv.conf
+const netnsEtcDir = "/etc/netns"
+
+// resolverFor returns the resolver for the host names of targets in the network namespace netns.
+// Inside a namespace the name server of its own resolv.conf is queried from within the namespace,
+// a VPN or VRF often has a name server that can not be reached from the outside.
+func resolverFor(netns string) *net.Resolver {
+	if netns == "" {
+		return net.DefaultResolver
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server, err := netnsNameserver(netns)
+			if err != nil {
+				return nil, err
+			}
+
+			var conn net.Conn
+			err = inNetns(netns, func() (err error) {
+				var dialer net.Dialer
+				conn, err = dialer.DialContext(ctx, network, server)
+				return err
+			})
+			return conn, err
+		},
+	}
+}
+
+// netnsNameserver returns the address of the first name server of the namespace netns.
+// Namespaces without a resolv.conf of their own use the one of the host, like ip netns exec.
+func netnsNameserver(netns string) (string, error) {
+	conf, err := os.ReadFile(filepath.Join(netnsEtcDir, netns, "resolv.conf"))
+	if os.IsNotExist(err) {
+		conf, err = os.ReadFile("/etc/resolv.conf")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	server, ok := firstNameserver(conf)
+	if !ok {
+		return "", fmt.Errorf("no name server for network namespace %s", netns)
+	}
+	return net.JoinHostPort(server, "53"), nil
+}
+
+// firstNameserver returns the first name server of a resolv.conf
+func firstNameserver(conf []byte) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(conf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
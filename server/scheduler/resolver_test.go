@@ -0,0 +1,23 @@
+package scheduler
+
+import (
+	"net"
+	"testing"
+)
+
+func TestFirstNameserver(t *testing.T) {
+	conf := []byte("# generated by the VPN client\nsearch corp.example\nnameserver not-an-ip\nnameserver 10.8.0.1\nnameserver 1.1.1.1\n")
+	if server, ok := firstNameserver(conf); !ok || server != "10.8.0.1" {
+		t.Errorf("firstNameserver() = %q, %v, want 10.8.0.1", server, ok)
+	}
+
+	if _, ok := firstNameserver([]byte("search corp.example\n")); ok {
+		t.Error("firstNameserver() found a name server in a resolv.conf without one")
+	}
+}
+
+func TestResolverFor(t *testing.T) {
+	if resolverFor("") != net.DefaultResolver {
+		t.Error("resolverFor() without a namespace should return the default resolver")
+	}
+}
@@ -8,47 +8,81 @@ import (
 	"math"
 	"sync"
 	"time"
-
-	probing "github.com/prometheus-community/pro-bing"
 )
 
+// syncInterval is the time between two lookups of added, changed or deleted targets
+const syncInterval = 5 * time.Second
+
 type Factors struct {
 	Fac15m float64
 	Fac6h  float64
 	Fac24h float64
 }
 
+// newFactors returns the decay factors of the exponential moving averages
+// for a target that is probed every interval.
+func newFactors(interval time.Duration) Factors {
+	return Factors{
+		Fac15m: math.Exp(-interval.Seconds() / (15 * 60)),
+		Fac6h:  math.Exp(-interval.Seconds() / (6 * 60 * 60)),
+		Fac24h: math.Exp(-interval.Seconds() / (24 * 60 * 60)),
+	}
+}
+
 type Scheduler struct {
 	db       database.DB
+	scheme   model.BucketScheme
 	wg       sync.WaitGroup
 	reload   chan struct{}
 	shutdown chan struct{}
-	interval int64
-	factors  Factors
+	// runners contains the running probe loop of every target by uuid.
+	// Only accessed by the scheduler goroutine.
+	runners map[string]*runner
+	// slots limits the number of probes in flight, every probe holds one while it runs
+	slots chan struct{}
+	// echo holds the ICMP sockets that are shared by all ICMP probes
+	echo *echoEngine
+	// store holds the statistics of all targets and writes them behind every flushInterval
+	store         *statsStore
+	flushInterval time.Duration
 }
 
-func NewScheduler(db database.DB) *Scheduler {
+// runner is the probe loop of a single target
+type runner struct {
+	target model.Target
+	cancel context.CancelFunc
+	// done is closed when the probe loop returned
+	done chan struct{}
+}
+
+// stop ends the probe loop and waits until it returned,
+// so the loop of a changed target never overlaps with the old one
+func (r *runner) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// NewScheduler returns a scheduler that runs at most maxProbes probes at the same time
+// and writes the statistics and results to the database every flushInterval
+func NewScheduler(db database.DB, scheme model.BucketScheme, maxProbes int, flushInterval time.Duration) *Scheduler {
 	reload := make(chan struct{})
 	shutdown := make(chan struct{})
 
-	var interval int64 = 15
 	return &Scheduler{
 		db:       db,
+		scheme:   scheme,
 		reload:   reload,
 		shutdown: shutdown,
-		interval: interval,
-		factors: Factors{
-			Fac15m: math.Exp(-float64(interval) / (15 * 60)),
-			Fac6h:  math.Exp(-float64(interval) / (6 * 60 * 60)),
-			Fac24h: math.Exp(-float64(interval) / (24 * 60 * 60)),
-		},
+		runners:  make(map[string]*runner),
+		slots:    make(chan struct{}, maxProbes),
+		echo:     newEchoEngine(),
+
+		store:         newStatsStore(db),
+		flushInterval: flushInterval,
 	}
 }
 
 func (s *Scheduler) StartScheduler(parent context.Context) {
-	timeout := time.Duration(10 * time.Second)
-	interval := time.Duration(s.interval) * time.Second
-
 	s.wg.Add(1)
 	go func() {
 
@@ -57,11 +91,24 @@ func (s *Scheduler) StartScheduler(parent context.Context) {
 		ctx, cancel := context.WithCancel(parent)
 		defer cancel()
 
-		ticker := time.NewTicker(interval)
+		ticker := time.NewTicker(syncInterval)
 		defer ticker.Stop()
 
-		// Run the first ping immediately
-		s.runPings(ctx, timeout)
+		pathTicker := time.NewTicker(pathInterval)
+		defer pathTicker.Stop()
+
+		flushTicker := time.NewTicker(s.flushInterval)
+		defer flushTicker.Stop()
+
+		// Continue with the stored statistics, from here on the scheduler owns them
+		err := s.store.load()
+		if err != nil {
+			fmt.Println("Error loading stats", err)
+		}
+
+		// Start probing and schedule the first path discoveries
+		s.syncTargets(ctx)
+		s.discoverPaths(ctx)
 
 		for {
 			select {
@@ -75,7 +122,13 @@ func (s *Scheduler) StartScheduler(parent context.Context) {
 				}
 
 			case <-ticker.C:
-				s.runPings(ctx, timeout)
+				s.syncTargets(ctx)
+
+			case <-pathTicker.C:
+				s.discoverPaths(ctx)
+
+			case <-flushTicker.C:
+				s.flush()
 			}
 		}
 
@@ -87,167 +140,501 @@ func (s *Scheduler) StopScheduler() {
 	close(s.reload)
 
 	s.wg.Wait()
+	s.echo.Close()
+
+	// Write what the probe loops stored since the last flush
+	s.flush()
 }
 
-func (s *Scheduler) runPings(ctx context.Context, timeout time.Duration) error {
+// flush writes the statistics and results of the probes to the database
+func (s *Scheduler) flush() {
+	err := s.store.flush()
+	if err != nil {
+		fmt.Println("Error saving stats", err)
+	}
+}
+
+// syncTargets starts a probe loop for every new target, stops the loops of
+// deleted targets and restarts the loops of targets whose settings changed.
+func (s *Scheduler) syncTargets(ctx context.Context) error {
 	targets, err := s.db.GetTargets()
 	if err != nil {
 		fmt.Println("Error getting targets", err)
 		return err
 	}
 
-	if len(targets) == 0 {
-		fmt.Println("No targets found")
-		return nil
+	current := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		current[target.Uuid] = true
+
+		r, ok := s.runners[target.Uuid]
+		if ok && r.target == *target {
+			continue
+		}
+		if ok {
+			r.stop()
+		}
+
+		targetCtx, cancel := context.WithCancel(ctx)
+		r = &runner{target: *target, cancel: cancel, done: make(chan struct{})}
+		s.runners[target.Uuid] = r
+
+		s.wg.Add(1)
+		go func(target model.Target, done chan struct{}) {
+			defer s.wg.Done()
+			defer close(done)
+			s.runTarget(targetCtx, &target)
+		}(*target, r.done)
 	}
 
-	wg := sync.WaitGroup{}
-	for _, target := range targets {
-		wg.Add(1)
-		go func(target *model.Target) {
-			defer wg.Done()
+	for uuid, r := range s.runners {
+		if !current[uuid] {
+			r.stop()
+			s.store.forget(uuid)
+			delete(s.runners, uuid)
+		}
+	}
 
-			pinger, err := probing.NewPinger(target.Address)
-			if err != nil {
-				// Most of the time this happens if we can't resolve the hostname
-				fmt.Printf("Error creating pinger for %s: %v\n", target.Address, err)
+	return nil
+}
 
-				dbStats, err := s.db.GetStatsByUuid(target.Uuid)
-				if err != nil {
-					fmt.Printf("Error getting stats for %s: %v\n", target.Address, err)
-					return
-				}
+// runTarget probes the target on its own interval until ctx is canceled.
+// Every target starts at its own offset within the interval, see startOffset.
+func (s *Scheduler) runTarget(ctx context.Context, target *model.Target) {
+	interval := target.IntervalDuration()
+	timeout := target.TimeoutDuration()
+	factors := newFactors(interval)
+
+	// Continue with the stored state, a restart should not end an outage
+	machine := newStateMachine(target)
+	stats := s.store.get(target.Uuid)
+	if stats != nil {
+		machine.seed(stats.State)
+	}
 
-				if dbStats == nil {
-					// We do not have any stats for this target yet
-					dbStats = &model.Stats{
-						TargetUuid: target.Uuid,
-						Max:        0,
-					}
-				}
+	// Results of other probe settings must not be mixed into the averages and histograms
+	reset := stats != nil && stats.ProbeSettings != target.Settings()
+	if reset {
+		fmt.Printf("Probe settings of %s changed, statistics start over\n", target.Address)
+		s.store.reset(target)
+	}
 
-				// Target is down so we do not modify min, max or the buckets
-				dbStats.Sent++
-				dbStats.Loss++
-				dbStats.State = "down"
-				dbStats.Timestamp = time.Now().Unix()
+	// Dual-stack targets keep separate statistics for IPv4 and IPv6
+	var families map[string]*model.FamilyStats
+	if target.DualStack {
+		families = s.loadFamilyStats(target)
+	}
 
-				s.db.SaveLoss(&model.Loss{
-					TargetUuid: target.Uuid,
-					Timestamp:  time.Now().Unix(),
-				})
+	offset := startOffset(target.Uuid, interval)
+	timer := time.NewTimer(time.Until(nextStart(time.Now(), interval, offset)))
+	defer timer.Stop()
 
-				s.db.SaveStats(*dbStats)
-				return
-			}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
 
-			pinger.Timeout = timeout
-			pinger.Count = 1
+		if !s.acquireSlot(ctx) {
+			return
+		}
+		var result Result
+		if target.DualStack {
+			result = s.probeDualStack(ctx, target, timeout, factors, families)
+		} else {
+			result = s.proberFor(target).Probe(ctx, target, timeout)
+		}
+		s.releaseSlot()
+		if ctx.Err() != nil {
+			// The target was deleted or changed while we were waiting for the result
+			return
+		}
+		if result.Err != nil {
+			fmt.Printf("Error probing %s: %v\n", target.Address, result.Err)
+		}
 
-			pinger.OnFinish = func(stats *probing.Statistics) {
-				dbStats, err := s.db.GetStatsByUuid(target.Uuid)
-				if err != nil {
-					fmt.Printf("Error getting stats for %s: %v\n", target.Address, err)
-					return
-				}
+		s.saveResult(target, result, factors, machine)
 
-				//currentLatency := float64(stats.MaxRtt.Milliseconds())
+		// A probe that waited long for a slot or ran late skips the slots it missed
+		timer.Reset(time.Until(nextStart(time.Now(), interval, offset)))
+	}
+}
 
-				// Convert MaxRtt to milliseconds with floating point precision
-				currentLatency := float64(stats.MaxRtt) / float64(time.Millisecond)
+// saveResult updates the statistics of the target with the result of a probe
+// and stores the latency or loss.
+func (s *Scheduler) saveResult(target *model.Target, result Result, factors Factors, machine *stateMachine) {
+	dbStats := s.store.get(target.Uuid)
 
-				if dbStats == nil {
-					// We do not have any stats for this target yet
-					dbStats = &model.Stats{
-						TargetUuid: target.Uuid,
-						Max:        currentLatency,
-					}
-				}
+	currentLatency := result.Latency
 
-				dbStats.Sent++
-				dbStats.State = "up"
-				if stats.PacketLoss > 0 {
-					// Target is down
-					dbStats.Loss++
-					dbStats.State = "down"
-
-					err = s.db.SaveLoss(&model.Loss{
-						TargetUuid: target.Uuid,
-						Timestamp:  time.Now().Unix(),
-					})
-					if err != nil {
-						fmt.Printf("Error saving loss for %s: %v\n", target.Address, err)
-					}
-
-				} else {
-					dbStats.Recv++
-				}
+	if dbStats == nil {
+		// We do not have any stats for this target yet
+		dbStats = &model.Stats{
+			TargetUuid: target.Uuid,
+			Max:        currentLatency,
 
-				min := currentLatency
-				if dbStats.Min.Valid && currentLatency > 0 {
-					min = math.Min(dbStats.Min.Float64, currentLatency)
-				} else if dbStats.Min.Valid && dbStats.Min.Float64 > 0 && currentLatency == 0 {
-					min = dbStats.Min.Float64
-				}
+			ProbeSettings: target.Settings(),
+		}
+	}
 
-				// Basically this is a Go version of of the original meshping code
-				// by Michael Ziegler (Svedrin)
-				// https://github.com/Svedrin/meshping/blob/8f6334ab3c362531be6c43fdad67ec321daa2d18/src/meshping.py#L199-L213
-				// He is my brother, so I guess it's ok to steal it
-				// (👉ﾟヮﾟ)👉
-				dbStats.Last = currentLatency
-				dbStats.Sum += dbStats.Last
-				dbStats.Max = math.Max(dbStats.Max, currentLatency)
-				dbStats.Min.Scan(min)
-				dbStats.Avg15m = s.expAvg(dbStats.Avg15m, currentLatency, s.factors.Fac15m)
-				dbStats.Avg6h = s.expAvg(dbStats.Avg6h, currentLatency, s.factors.Fac6h)
-				dbStats.Avg24h = s.expAvg(dbStats.Avg24h, currentLatency, s.factors.Fac24h)
-				dbStats.Timestamp = time.Now().Unix()
-
-				err = s.db.SaveStats(*dbStats)
-				if err != nil {
-					fmt.Printf("Error saving stats for %s: %v\n", target.Address, err)
-				}
+	sent, recv := result.packets()
+	samples := result.samples()
+
+	// The jitter continues from the last reply of the previous probe,
+	// unless the previous probe was lost.
+	hasPrevious := machine.replied > 0
+	dbStats.Jitter = updateJitter(dbStats.Jitter, dbStats.Last, hasPrevious, samples)
+	dbStats.BurstMin, dbStats.BurstAvg, dbStats.BurstMax = burstStats(samples)
+	dbStats.BurstLoss = float64(sent-recv) / float64(sent) * 100
+
+	dbStats.Loss15m = s.expAvg(dbStats.Loss15m, dbStats.BurstLoss, factors.Fac15m)
+	dbStats.Loss6h = s.expAvg(dbStats.Loss6h, dbStats.BurstLoss, factors.Fac6h)
+	dbStats.Loss24h = s.expAvg(dbStats.Loss24h, dbStats.BurstLoss, factors.Fac24h)
+
+	// Loss lowers the R-factor, so it is updated for lost probes as well
+	r := rFactor(currentLatency, dbStats.Jitter, dbStats.BurstLoss)
+	if dbStats.Mos24h == 0 {
+		// Start with the first value, otherwise the averages would need hours to
+		// climb up from a MOS of 0 which does not exist.
+		dbStats.RFactor15m, dbStats.RFactor6h, dbStats.RFactor24h = r, r, r
+	} else {
+		dbStats.RFactor15m = s.expAvg(dbStats.RFactor15m, r, factors.Fac15m)
+		dbStats.RFactor6h = s.expAvg(dbStats.RFactor6h, r, factors.Fac6h)
+		dbStats.RFactor24h = s.expAvg(dbStats.RFactor24h, r, factors.Fac24h)
+	}
+	dbStats.Mos15m = mos(dbStats.RFactor15m)
+	dbStats.Mos6h = mos(dbStats.RFactor6h)
+	dbStats.Mos24h = mos(dbStats.RFactor24h)
+
+	dbStats.Sent += uint64(sent)
+	dbStats.Recv += uint64(recv)
+	dbStats.Loss += float64(sent - recv)
+	dbStats.Late += uint64(len(result.Late))
+	dbStats.Duplicates += uint64(result.Duplicates)
+	dbStats.Reordered += uint64(result.Reordered)
+	dbStats.Corrupted += uint64(result.Corrupted)
+	dbStats.Timestamp = time.Now().Unix()
+
+	wasDown := machine.down()
+	previous := dbStats.State
+	machine.update(result, dbStats.Timestamp)
+	dbStats.State = machine.State()
+
+	if dbStats.State != previous {
+		s.store.addStateChange(model.StateChange{
+			TargetUuid: target.Uuid,
+			Timestamp:  dbStats.Timestamp,
+			State:      dbStats.State,
+			Previous:   previous,
+		})
+	}
 
-				if dbStats.State == "up" {
-					s.db.SaveLatency(&model.Latency{
-						TargetUuid: target.Uuid,
-						Timestamp:  time.Now().Unix(),
-						Latency:    currentLatency,
-					})
-
-					// The plan is to use eCharts to display the histogram
-					// intead of the original meshping implementation I simplified this
-					// Original would be: int64(math.Log2(currentLatency) * 10)
-					//
-					// I on the other hand just use the last two digits of the latency to create the bucket
-
-					s.db.SaveMeasurement(&model.HistogramMeasurement{
-						TargetUuid: target.Uuid,
-						Timestamp:  int64(time.Now().Unix()/3600) * 3600,
-						Bucket:     roundFloat(currentLatency, 2.),
-					})
-				}
+	s.updateIncident(target, machine, wasDown, int64(sent-recv), dbStats.Timestamp)
+
+	s.savePacketEvents(target, result, dbStats.Timestamp)
+
+	for _, reply := range result.Late {
+		reply.TargetUuid = target.Uuid
+		reply.Timestamp = dbStats.Timestamp
+		s.store.addLateReply(reply)
+	}
+
+	// Dual-stack targets track the address of each family on its own
+	if result.Address != "" && !target.DualStack {
+		s.saveResolution(target, "", dbStats.Address, result.Address, dbStats.Timestamp)
+		dbStats.Address = result.Address
+	}
+
+	if result.Lost {
+		// No reply so we do not modify min, max or the buckets
+		s.store.addLoss(model.Loss{
+			TargetUuid: target.Uuid,
+			Timestamp:  time.Now().Unix(),
+			Rcode:      result.Rcode,
+			Reason:     result.reason(),
+			ICMPType:   result.ICMPType,
+			ICMPCode:   result.ICMPCode,
+			Address:    result.Address,
+
+			ProbeSettings: target.Settings(),
+		})
+
+		s.store.put(*dbStats)
+		return
+	}
+
+	min := dbStats.BurstMin
+	if dbStats.Min.Valid && min > 0 {
+		min = math.Min(dbStats.Min.Float64, min)
+	} else if dbStats.Min.Valid && dbStats.Min.Float64 > 0 && min == 0 {
+		min = dbStats.Min.Float64
+	}
+
+	// Basically this is a Go version of of the original meshping code
+	// by Michael Ziegler (Svedrin)
+	// https://github.com/Svedrin/meshping/blob/8f6334ab3c362531be6c43fdad67ec321daa2d18/src/meshping.py#L199-L213
+	// He is my brother, so I guess it's ok to steal it
+	// (👉ﾟヮﾟ)👉
+	dbStats.Last = samples[len(samples)-1]
+	for _, sample := range samples {
+		dbStats.Sum += sample
+	}
+	dbStats.Max = math.Max(dbStats.Max, dbStats.BurstMax)
+	dbStats.Min.Scan(min)
+	dbStats.Avg15m = s.expAvg(dbStats.Avg15m, currentLatency, factors.Fac15m)
+	dbStats.Avg6h = s.expAvg(dbStats.Avg6h, currentLatency, factors.Fac6h)
+	dbStats.Avg24h = s.expAvg(dbStats.Avg24h, currentLatency, factors.Fac24h)
+
+	// The fastest family of a dual-stack target can change from probe to probe,
+	// and IPv4 and IPv6 rarely take the same number of hops
+	if result.TTL > 0 && !target.DualStack {
+		// A different number of hops means the route to the target changed
+		hops := hopCount(result.TTL)
+		if dbStats.TTL > 0 && hops != dbStats.Hops {
+			s.store.addPathChange(model.PathChange{
+				TargetUuid:   target.Uuid,
+				Timestamp:    dbStats.Timestamp,
+				PreviousHops: dbStats.Hops,
+				Hops:         hops,
+			})
+		}
+		dbStats.TTL = result.TTL
+		dbStats.Hops = hops
+	}
+
+	s.store.put(*dbStats)
+
+	s.store.addLatency(model.Latency{
+		TargetUuid: target.Uuid,
+		Timestamp:  time.Now().Unix(),
+		Latency:    currentLatency,
+		Rcode:      result.Rcode,
+		TTL:        result.TTL,
+		Address:    result.Address,
+
+		ProbeSettings: target.Settings(),
+	})
+
+	s.store.addJitter(model.Jitter{
+		TargetUuid: target.Uuid,
+		Timestamp:  time.Now().Unix(),
+		Jitter:     dbStats.Jitter,
 
+		ProbeSettings: target.Settings(),
+	})
+
+	if result.HTTPTiming != nil {
+		result.HTTPTiming.TargetUuid = target.Uuid
+		result.HTTPTiming.Timestamp = time.Now().Unix()
+
+		s.store.addHTTPTiming(*result.HTTPTiming)
+	}
+
+	// The plan is to use eCharts to display the histogram.
+	// Like meshping the buckets grow exponentially by default: int64(math.Log2(currentLatency) * 10)
+	// The bucket is stored as its lower bound in ms, so the chart can show it as is.
+
+	for _, sample := range samples {
+		s.store.addMeasurement(model.HistogramMeasurement{
+			TargetUuid: target.Uuid,
+			Timestamp:  s.scheme.Timestamp(time.Now().Unix()),
+			Bucket:     s.scheme.Bucket(sample),
+		})
+	}
+}
+
+// savePacketEvents stores the duplicated, reordered and corrupted replies of a probe
+func (s *Scheduler) savePacketEvents(target *model.Target, result Result, timestamp int64) {
+	counts := []struct {
+		kind  string
+		count int
+	}{
+		{model.EventDuplicate, result.Duplicates},
+		{model.EventReordered, result.Reordered},
+		{model.EventCorrupted, result.Corrupted},
+	}
+
+	for _, c := range counts {
+		if c.count == 0 {
+			continue
+		}
+
+		s.store.addPacketEvent(model.PacketEvent{
+			TargetUuid: target.Uuid,
+			Timestamp:  timestamp,
+			Kind:       c.kind,
+			Count:      c.count,
+		})
+	}
+}
+
+// saveResolution records a change of the address the host name of target resolves to.
+// family is empty for targets that are not dual-stack.
+func (s *Scheduler) saveResolution(target *model.Target, family, previous, address string, timestamp int64) {
+	if previous == "" || previous == address {
+		return
+	}
+
+	s.store.addResolutionChange(model.ResolutionChange{
+		TargetUuid:      target.Uuid,
+		Timestamp:       timestamp,
+		Family:          family,
+		PreviousAddress: previous,
+		Address:         address,
+	})
+}
+
+// updateIncident opens an incident when a target goes down, counts the lost
+// packets while it stays down and closes the incident when it is up again.
+// The incident covers the time from the first lost probe to the first reply.
+func (s *Scheduler) updateIncident(target *model.Target, machine *stateMachine, wasDown bool, lost int64, now int64) {
+	isDown := machine.down()
+	if !wasDown && !isDown {
+		return
+	}
+
+	var incident *model.Incident
+	if !wasDown {
+		incident = &model.Incident{
+			TargetUuid: target.Uuid,
+			Start:      machine.failingSince,
+			Packets:    machine.streakLost,
+		}
+	} else {
+		incident = s.store.openIncident(target.Uuid)
+		if incident == nil {
+			// The target went down before incidents were recorded
+			return
+		}
+
+		if isDown {
+			incident.Packets += lost
+		} else {
+			incident.End = machine.replyingSince
+		}
+	}
+
+	if incident.End != 0 {
+		incident.Duration = incident.End - incident.Start
+	} else {
+		incident.Duration = now - incident.Start
+	}
+
+	s.store.putIncident(*incident)
+}
+
+// discoverPaths runs the traceroute and the path MTU discovery of all targets that have them enabled.
+// Like the probes they are spread across pathInterval by startOffset and hold a slot while they run.
+func (s *Scheduler) discoverPaths(ctx context.Context) {
+	targets, err := s.db.GetTargets()
+	if err != nil {
+		fmt.Println("Error getting targets", err)
+		return
+	}
+
+	now := time.Now()
+	for _, target := range targets {
+		if !target.Traceroute && !target.MTUDiscovery {
+			continue
+		}
+
+		start := nextStart(now, pathInterval, startOffset(target.Uuid, pathInterval))
+		s.wg.Add(1)
+		go func(target *model.Target) {
+			defer s.wg.Done()
+
+			timer := time.NewTimer(time.Until(start))
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
 			}
 
-			err = pinger.RunWithContext(ctx)
-			if err != nil {
-				fmt.Printf("Error running pinger for %s: %v\n", target.Address, err)
+			if !s.acquireSlot(ctx) {
 				return
 			}
+			defer s.releaseSlot()
 
+			if target.Traceroute {
+				s.runTraceroute(ctx, target)
+			}
+			if target.MTUDiscovery {
+				s.runPathMTUDiscovery(ctx, target)
+			}
 		}(target)
 	}
+}
 
-	return nil
+// runTraceroute discovers the path to the target and stores its hops
+func (s *Scheduler) runTraceroute(ctx context.Context, target *model.Target) {
+	timestamp := time.Now().Unix()
+	hops, err := Traceroute(ctx, target)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error running traceroute for %s: %v\n", target.Address, err)
+		return
+	}
+
+	for i := range hops {
+		hops[i].TargetUuid = target.Uuid
+		hops[i].Timestamp = timestamp
+	}
+
+	err = s.db.SaveHops(hops)
+	if err != nil {
+		fmt.Printf("Error saving hops for %s: %v\n", target.Address, err)
+	}
 }
 
-func (s *Scheduler) expAvg(current_avg, new_value, factor float64) float64 {
-	return (current_avg * factor) + (new_value * (1 - factor))
+// runPathMTUDiscovery discovers the path MTU to the target and stores it
+func (s *Scheduler) runPathMTUDiscovery(ctx context.Context, target *model.Target) {
+	timestamp := time.Now().Unix()
+	mtu, err := DiscoverPathMTU(ctx, target)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error discovering the path MTU for %s: %v\n", target.Address, err)
+		return
+	}
+
+	s.savePathMTU(target, timestamp, mtu)
 }
 
-func roundFloat(value float64, precision float64) float64 {
-	ratio := math.Pow(10, precision)
-	return math.Round(value*ratio) / ratio
+// savePathMTU stores a discovered path MTU and records a change of it
+func (s *Scheduler) savePathMTU(target *model.Target, timestamp int64, mtu int) {
+	previous, err := s.db.GetLastPathMTU(target.Uuid)
+	if err != nil {
+		fmt.Printf("Error getting the last path MTU for %s: %v\n", target.Address, err)
+		return
+	}
+
+	err = s.db.SavePathMTU(&model.PathMTU{TargetUuid: target.Uuid, Timestamp: timestamp, MTU: mtu})
+	if err != nil {
+		fmt.Printf("Error saving path MTU for %s: %v\n", target.Address, err)
+		return
+	}
+
+	if previous == nil || previous.MTU == mtu {
+		return
+	}
+
+	fmt.Printf("Path MTU to %s changed from %d to %d bytes\n", target.Address, previous.MTU, mtu)
+	err = s.db.SaveMTUChange(&model.MTUChange{
+		TargetUuid:  target.Uuid,
+		Timestamp:   timestamp,
+		PreviousMTU: previous.MTU,
+		MTU:         mtu,
+	})
+	if err != nil {
+		fmt.Printf("Error saving path MTU change for %s: %v\n", target.Address, err)
+	}
+}
+
+func (s *Scheduler) expAvg(current_avg, new_value, factor float64) float64 {
+	return (current_avg * factor) + (new_value * (1 - factor))
 }
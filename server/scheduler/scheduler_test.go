@@ -0,0 +1,21 @@
+package scheduler
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewFactors_IndependentOfInterval(t *testing.T) {
+	// After 15 minutes the weight of the old average must be the same,
+	// no matter how often the target was probed in between.
+	for _, interval := range []time.Duration{time.Second, 15 * time.Second, time.Minute} {
+		factors := newFactors(interval)
+		samples := float64(15*time.Minute) / float64(interval)
+
+		got := math.Pow(factors.Fac15m, samples)
+		if math.Abs(got-math.Exp(-1)) > 1e-9 {
+			t.Errorf("interval %v: weight after 15m = %v, want %v", interval, got, math.Exp(-1))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// controlSocket applies opts to a socket before it is bound
+func controlSocket(c syscall.RawConn, v6 bool, opts socketOptions) error {
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		err = setSocketOptions(int(fd), v6, opts)
+	})
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// setSocketOptions applies the options that golang.org/x/net does not offer
+func setSocketOptions(fd int, v6 bool, opts socketOptions) error {
+	if opts.device != "" {
+		if err := syscall.BindToDevice(fd, opts.device); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+
+	if opts.dontFragment {
+		// Never fragment, packets larger than the path MTU fail instead
+		level, name, value := syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_DO
+		if v6 {
+			level, name, value = syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_DO
+		}
+		if err := syscall.SetsockoptInt(fd, level, name, value); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	return nil
+}
+
+// listenPing opens an unprivileged ping socket with opts. Unlike icmp.ListenPacket
+// it applies the options to the socket before it is bound.
+func listenPing(v6 bool, opts socketOptions) (*echoConn, error) {
+	family, proto := syscall.AF_INET, protocolICMP
+	var addr syscall.Sockaddr
+	if v6 {
+		family, proto = syscall.AF_INET6, protocolIPv6ICMP
+		sa := &syscall.SockaddrInet6{}
+		copy(sa.Addr[:], opts.source.To16())
+		addr = sa
+	} else {
+		sa := &syscall.SockaddrInet4{}
+		copy(sa.Addr[:], opts.source.To4())
+		addr = sa
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	err = setSocketOptions(fd, v6, opts)
+	if err == nil {
+		if bindErr := syscall.Bind(fd, addr); bindErr != nil {
+			err = os.NewSyscallError("bind", bindErr)
+		}
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "ping socket")
+	defer f.Close()
+
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, err
+	}
+	return newEchoConn(conn, v6, false), nil
+}
@@ -0,0 +1,47 @@
+//go:build !linux
+
+package scheduler
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/net/icmp"
+)
+
+var (
+	errDontFragment = errors.New("the don't fragment flag is only supported on Linux")
+	errBindToDevice = errors.New("binding to an interface is only supported on Linux")
+)
+
+// controlSocket applies opts to a socket before it is bound
+func controlSocket(c syscall.RawConn, v6 bool, opts socketOptions) error {
+	switch {
+	case opts.dontFragment:
+		return errDontFragment
+	case opts.device != "":
+		return errBindToDevice
+	}
+	return nil
+}
+
+// listenPing opens an unprivileged ping socket
+func listenPing(v6 bool, opts socketOptions) (*echoConn, error) {
+	if err := controlSocket(nil, v6, opts); err != nil {
+		return nil, err
+	}
+
+	network, address := "udp4", "0.0.0.0"
+	if v6 {
+		network, address = "udp6", "::"
+	}
+	if opts.source != nil {
+		address = opts.source.String()
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &echoConn{PacketConn: conn, v4: conn.IPv4PacketConn(), v6: conn.IPv6PacketConn()}, nil
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+// DefaultMaxProbes is the number of probes that may be in flight at the same time
+const DefaultMaxProbes = 256
+
+// startOffset returns the position of a target within its interval. It is derived
+// from the uuid, so the probes of all targets are spread across the interval
+// instead of all starting at the same moment, and keep their position after a restart.
+func startOffset(uuid string, interval time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(uuid))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// nextStart returns the first time after now at which a target with offset is probed.
+// The slots are aligned to the clock, so targets with the same interval never drift together.
+func nextStart(now time.Time, interval, offset time.Duration) time.Time {
+	next := now.Truncate(interval).Add(offset)
+	for !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next
+}
+
+// acquireSlot waits until less than maxProbes probes are in flight.
+// Returns false if ctx was canceled while waiting.
+func (s *Scheduler) acquireSlot(ctx context.Context) bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseSlot frees the slot of a finished probe
+func (s *Scheduler) releaseSlot() {
+	<-s.slots
+}
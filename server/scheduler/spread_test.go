@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStartOffset_Spread(t *testing.T) {
+	interval := 15 * time.Second
+
+	// 1000 targets fall evenly into the tenths of the interval
+	buckets := make([]int, 10)
+	for i := 0; i < 1000; i++ {
+		uuid := fmt.Sprintf("00000000-0000-0000-0000-%012d", i)
+		offset := startOffset(uuid, interval)
+		if offset < 0 || offset >= interval {
+			t.Fatalf("offset %v is outside of the interval", offset)
+		}
+		if offset != startOffset(uuid, interval) {
+			t.Fatalf("offset of %s is not deterministic", uuid)
+		}
+		buckets[offset*10/interval]++
+	}
+
+	for i, n := range buckets {
+		if n < 50 || n > 150 {
+			t.Errorf("%d of 1000 targets start in tenth %d of the interval, want about 100", n, i)
+		}
+	}
+}
+
+func TestNextStart(t *testing.T) {
+	interval := 10 * time.Second
+	offset := 3 * time.Second
+	base := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{base, base.Add(3 * time.Second)},
+		{base.Add(2 * time.Second), base.Add(3 * time.Second)},
+		// A probe that starts exactly on its slot waits for the next one
+		{base.Add(3 * time.Second), base.Add(13 * time.Second)},
+		{base.Add(9 * time.Second), base.Add(13 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		if got := nextStart(tt.now, interval, offset); !got.Equal(tt.want) {
+			t.Errorf("nextStart(%v) = %v, want %v", tt.now.Sub(base), got.Sub(base), tt.want.Sub(base))
+		}
+	}
+}
+
+func TestAcquireSlot(t *testing.T) {
+	s := &Scheduler{slots: make(chan struct{}, 2)}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if !s.acquireSlot(ctx) || !s.acquireSlot(ctx) {
+		t.Fatal("acquireSlot() should return the two free slots")
+	}
+	if s.acquireSlot(ctx) {
+		t.Fatal("acquireSlot() should wait while all slots are taken")
+	}
+
+	s.releaseSlot()
+	if !s.acquireSlot(context.Background()) {
+		t.Error("acquireSlot() should return a released slot")
+	}
+}
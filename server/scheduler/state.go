@@ -0,0 +1,159 @@
+package scheduler
+
+import "lagident/model"
+
+// Flap detection works like in Nagios: the transition rate is the share of
+// probes in the history whose outcome differs from the probe before. A target
+// starts flapping above flapStart and stops flapping below flapStop.
+const (
+	flapHistory = 21
+	flapStart   = 0.5
+	flapStop    = 0.25
+)
+
+// stateMachine decides the state of a target from consecutive probe results,
+// so a single lost probe does not mark a target down.
+type stateMachine struct {
+	failures  int
+	successes int
+	degraded  float64
+	// slowReplies and fastReplies are the thresholds of the degraded state
+	slowReplies int
+	fastReplies int
+
+	// state is up, down or degraded, flapping is tracked on top of it
+	state    string
+	flapping bool
+
+	// Consecutive lost probes, replies, slow and fast replies
+	failed  int
+	replied int
+	slow    int
+	fast    int
+
+	// failingSince is the timestamp of the first lost probe in a row,
+	// streakLost the number of packets that were lost since then.
+	failingSince int64
+	streakLost   int64
+	// replyingSince is the timestamp of the first reply in a row
+	replyingSince int64
+
+	// history of the last probes, true if the probe got a reply
+	history []bool
+}
+
+func newStateMachine(target *model.Target) *stateMachine {
+	return &stateMachine{
+		failures:  target.Failures(),
+		successes: target.Successes(),
+		degraded:  target.DegradedLatency,
+
+		slowReplies: target.SlowReplies(),
+		fastReplies: target.FastReplies(),
+	}
+}
+
+// seed continues with the state that was stored before a restart
+func (m *stateMachine) seed(state string) {
+	switch state {
+	case model.StateFlapping:
+		// Assume the worst until the target proves to be up
+		m.state = model.StateDown
+		m.flapping = true
+	default:
+		m.state = state
+	}
+}
+
+// update feeds the result of a probe into the state machine
+func (m *stateMachine) update(result Result, now int64) {
+	m.history = append(m.history, !result.Lost)
+	if len(m.history) > flapHistory {
+		m.history = m.history[1:]
+	}
+
+	if result.Lost {
+		if m.failed == 0 {
+			m.failingSince = now
+			m.streakLost = 0
+		}
+		m.failed++
+		m.replied = 0
+	} else {
+		if m.replied == 0 {
+			m.replyingSince = now
+		}
+		m.replied++
+		m.failed = 0
+
+		if m.degraded > 0 && result.Latency > m.degraded {
+			m.slow++
+			m.fast = 0
+		} else {
+			m.fast++
+			m.slow = 0
+		}
+	}
+	sent, recv := result.packets()
+	m.streakLost += int64(sent - recv)
+
+	switch m.state {
+	case "":
+		// The first probe of a new target decides
+		if result.Lost {
+			m.state = model.StateDown
+		} else {
+			m.state = model.StateUp
+		}
+	case model.StateDown:
+		if m.replied >= m.successes {
+			m.state = model.StateUp
+		}
+	default:
+		if m.failed >= m.failures {
+			m.state = model.StateDown
+		}
+	}
+
+	if m.state == model.StateUp && m.slow >= m.slowReplies {
+		m.state = model.StateDegraded
+	} else if m.state == model.StateDegraded && m.fast >= m.fastReplies {
+		m.state = model.StateUp
+	}
+
+	rate := m.transitionRate()
+	if !m.flapping && rate >= flapStart {
+		m.flapping = true
+	} else if m.flapping && rate < flapStop {
+		m.flapping = false
+	}
+}
+
+// State returns the state that is shown to the user
+func (m *stateMachine) State() string {
+	if m.flapping {
+		return model.StateFlapping
+	}
+	return m.state
+}
+
+// down reports if the target is down, even if it is flapping
+func (m *stateMachine) down() bool {
+	return m.state == model.StateDown
+}
+
+// transitionRate returns the share of state changes in the probe history,
+// 0 until the history is complete.
+func (m *stateMachine) transitionRate() float64 {
+	if len(m.history) < flapHistory {
+		return 0
+	}
+
+	changes := 0
+	for i := 1; i < len(m.history); i++ {
+		if m.history[i] != m.history[i-1] {
+			changes++
+		}
+	}
+	return float64(changes) / float64(len(m.history)-1)
+}
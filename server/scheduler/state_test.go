@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"lagident/model"
+	"testing"
+)
+
+// feed runs the state machine over the probes, 'x' is a lost probe,
+// 's' a slow reply and '.' a reply. It returns the state after every probe.
+func feed(m *stateMachine, probes string) []string {
+	states := make([]string, 0, len(probes))
+	for i, p := range probes {
+		result := Result{Latency: 10}
+		switch p {
+		case 'x':
+			result = Result{Lost: true}
+		case 's':
+			result.Latency = 500
+		}
+		m.update(result, int64(i))
+		states = append(states, m.State())
+	}
+	return states
+}
+
+func TestStateMachine_Hysteresis(t *testing.T) {
+	m := newStateMachine(&model.Target{})
+	states := feed(m, "..xx.xxx.x..")
+
+	want := []string{"up", "up", "up", "up", "up", "up", "up", "down", "down", "down", "down", "up"}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+
+	if m.failingSince != 9 || m.replyingSince != 10 {
+		t.Errorf("failingSince = %d, replyingSince = %d, want 9 and 10", m.failingSince, m.replyingSince)
+	}
+}
+
+func TestStateMachine_Degraded(t *testing.T) {
+	m := newStateMachine(&model.Target{DegradedLatency: 100})
+	states := feed(m, ".sssss.s..")
+
+	want := []string{"up", "up", "up", "degraded", "degraded", "degraded", "degraded", "degraded", "degraded", "up"}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
+func TestStateMachine_DegradedThresholds(t *testing.T) {
+	// Degraded reacts faster than down and recovers slower than up
+	m := newStateMachine(&model.Target{DegradedLatency: 100, SlowThreshold: 1, FastThreshold: 3})
+	states := feed(m, ".s...")
+
+	want := []string{"up", "degraded", "degraded", "degraded", "up"}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
+func TestStateMachine_Flapping(t *testing.T) {
+	m := newStateMachine(&model.Target{FailThreshold: 1, SuccessThreshold: 1})
+
+	// Every other probe is lost
+	states := feed(m, ".x.x.x.x.x.x.x.x.x.x.")
+	if got := states[len(states)-1]; got != model.StateFlapping {
+		t.Fatalf("state = %v, want flapping", got)
+	}
+
+	// Stays flapping until the transition rate drops below flapStop
+	states = feed(m, "......")
+	if got := states[len(states)-1]; got != model.StateFlapping {
+		t.Fatalf("state = %v, want flapping", got)
+	}
+	states = feed(m, "..........")
+	if got := states[len(states)-1]; got != model.StateUp {
+		t.Fatalf("state = %v, want up", got)
+	}
+}
+
+func TestStateMachine_Seed(t *testing.T) {
+	m := newStateMachine(&model.Target{})
+	m.seed(model.StateDown)
+
+	// A stored down state needs the success threshold to come up again
+	states := feed(m, "..")
+	if states[0] != model.StateDown || states[1] != model.StateUp {
+		t.Errorf("states = %v, want [down up]", states)
+	}
+}
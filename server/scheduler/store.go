@@ -0,0 +1,388 @@
+package scheduler
+
+import (
+	"fmt"
+	"lagident/database"
+	"lagident/model"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultFlushInterval is the time between two writes of the statistics and results to the database
+const DefaultFlushInterval = 15 * time.Second
+
+// maxPendingResults is how many results of each kind are kept while the database cannot be written
+const maxPendingResults = 100000
+
+// statsStore keeps the authoritative statistics and open incidents of all targets
+// in memory. The statistics and the results and events of the probes are written
+// behind in batches, one transaction per flush instead of a read and several
+// writes per probe.
+type statsStore struct {
+	db database.DB
+
+	mu    sync.Mutex
+	stats map[string]model.Stats
+	// dirty marks the statistics that changed since the last flush
+	dirty map[string]bool
+	// families holds the statistics of each address family of dual-stack targets
+	families      map[familyKey]model.FamilyStats
+	dirtyFamilies map[familyKey]bool
+	// incidents holds the ongoing outage of every target that is down,
+	// changedIncidents the latest version of every incident that changed since the last flush
+	incidents        map[string]model.Incident
+	changedIncidents map[incidentKey]model.Incident
+	batch            model.Batch
+}
+
+// familyKey identifies the statistics of an address family of a target
+type familyKey struct {
+	uuid   string
+	family string
+}
+
+// incidentKey identifies an incident like the primary key of the incidents table
+type incidentKey struct {
+	uuid  string
+	start int64
+}
+
+func newStatsStore(db database.DB) *statsStore {
+	return &statsStore{
+		db:               db,
+		stats:            make(map[string]model.Stats),
+		dirty:            make(map[string]bool),
+		families:         make(map[familyKey]model.FamilyStats),
+		dirtyFamilies:    make(map[familyKey]bool),
+		incidents:        make(map[string]model.Incident),
+		changedIncidents: make(map[incidentKey]model.Incident),
+	}
+}
+
+// load seeds the store with the statistics and open incidents in the database
+func (s *statsStore) load() error {
+	stats, err := s.db.GetStats()
+	if err != nil {
+		return err
+	}
+	families, err := s.db.GetFamilyStats()
+	if err != nil {
+		return err
+	}
+	incidents, err := s.db.GetOpenIncidents()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stat := range stats {
+		s.stats[stat.TargetUuid] = *stat
+	}
+	for _, family := range families {
+		s.families[familyKey{family.TargetUuid, family.Family}] = family
+	}
+	// The latest open incident of a target wins
+	for _, incident := range incidents {
+		s.incidents[incident.TargetUuid] = incident
+	}
+	return nil
+}
+
+// get returns a copy of the statistics of a target, nil if it has none yet
+func (s *statsStore) get(uuid string) *model.Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.stats[uuid]
+	if !ok {
+		return nil
+	}
+	return &stats
+}
+
+// put replaces the statistics of a target, they are written with the next flush
+func (s *statsStore) put(stats model.Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats[stats.TargetUuid] = stats
+	s.dirty[stats.TargetUuid] = true
+}
+
+// family returns a copy of the statistics of an address family of a target, nil if it has none yet
+func (s *statsStore) family(uuid, family string) *model.FamilyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.families[familyKey{uuid, family}]
+	if !ok {
+		return nil
+	}
+	return &stats
+}
+
+// putFamily replaces the statistics of an address family, they are written with the next flush
+func (s *statsStore) putFamily(stats model.FamilyStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := familyKey{stats.TargetUuid, stats.Family}
+	s.families[key] = stats
+	s.dirtyFamilies[key] = true
+}
+
+// openIncident returns a copy of the ongoing outage of a target, nil if it is not down
+func (s *statsStore) openIncident(uuid string) *model.Incident {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, ok := s.incidents[uuid]
+	if !ok {
+		return nil
+	}
+	return &incident
+}
+
+// putIncident opens, updates or closes an incident, it is written with the next flush
+func (s *statsStore) putIncident(incident model.Incident) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if incident.End == 0 {
+		s.incidents[incident.TargetUuid] = incident
+	} else {
+		delete(s.incidents, incident.TargetUuid)
+	}
+	s.changedIncidents[incidentKey{incident.TargetUuid, incident.Start}] = incident
+}
+
+// forget drops the statistics, incidents and pending results of a deleted target,
+// so a flush does not write them again
+func (s *statsStore) forget(uuid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.stats, uuid)
+	delete(s.dirty, uuid)
+	delete(s.incidents, uuid)
+	for _, family := range model.Families {
+		delete(s.families, familyKey{uuid, family})
+		delete(s.dirtyFamilies, familyKey{uuid, family})
+	}
+	for key := range s.changedIncidents {
+		if key.uuid == uuid {
+			delete(s.changedIncidents, key)
+		}
+	}
+
+	s.batch.Latencies = slices.DeleteFunc(s.batch.Latencies, func(l model.Latency) bool { return l.TargetUuid == uuid })
+	s.batch.Losses = slices.DeleteFunc(s.batch.Losses, func(l model.Loss) bool { return l.TargetUuid == uuid })
+	s.batch.Jitters = slices.DeleteFunc(s.batch.Jitters, func(j model.Jitter) bool { return j.TargetUuid == uuid })
+	s.batch.HTTPTimings = slices.DeleteFunc(s.batch.HTTPTimings, func(t model.HTTPTiming) bool { return t.TargetUuid == uuid })
+	s.batch.Measurements = slices.DeleteFunc(s.batch.Measurements, func(m model.HistogramMeasurement) bool { return m.TargetUuid == uuid })
+	s.batch.HistogramResets = slices.DeleteFunc(s.batch.HistogramResets, func(reset string) bool { return reset == uuid })
+	s.batch.StateChanges = slices.DeleteFunc(s.batch.StateChanges, func(c model.StateChange) bool { return c.TargetUuid == uuid })
+	s.batch.LateReplies = slices.DeleteFunc(s.batch.LateReplies, func(r model.LateReply) bool { return r.TargetUuid == uuid })
+	s.batch.PacketEvents = slices.DeleteFunc(s.batch.PacketEvents, func(e model.PacketEvent) bool { return e.TargetUuid == uuid })
+	s.batch.PathChanges = slices.DeleteFunc(s.batch.PathChanges, func(c model.PathChange) bool { return c.TargetUuid == uuid })
+	s.batch.ResolutionChanges = slices.DeleteFunc(s.batch.ResolutionChanges, func(c model.ResolutionChange) bool { return c.TargetUuid == uuid })
+	s.batch.FamilyResults = slices.DeleteFunc(s.batch.FamilyResults, func(r model.FamilyResult) bool { return r.TargetUuid == uuid })
+}
+
+// reset starts the statistics, the family statistics and the histogram of a target over,
+// keeping only its state. Used when the probe settings changed, the old results would
+// distort the new ones.
+func (s *statsStore) reset(target *model.Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := model.Stats{TargetUuid: target.Uuid, ProbeSettings: target.Settings()}
+	if old, ok := s.stats[target.Uuid]; ok {
+		stats.State = old.State
+		stats.Address = old.Address
+	}
+	s.stats[target.Uuid] = stats
+	s.dirty[target.Uuid] = true
+
+	// Dual-stack targets start with empty ones on their next probe
+	for _, family := range model.Families {
+		delete(s.families, familyKey{target.Uuid, family})
+		delete(s.dirtyFamilies, familyKey{target.Uuid, family})
+	}
+
+	// Pending histogram counts were measured with the old settings
+	measurements := s.batch.Measurements[:0]
+	for _, m := range s.batch.Measurements {
+		if m.TargetUuid != target.Uuid {
+			measurements = append(measurements, m)
+		}
+	}
+	s.batch.Measurements = measurements
+	s.batch.HistogramResets = append(s.batch.HistogramResets, target.Uuid)
+}
+
+func (s *statsStore) addLatency(latency model.Latency) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.Latencies = append(s.batch.Latencies, latency)
+}
+
+func (s *statsStore) addLoss(loss model.Loss) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.Losses = append(s.batch.Losses, loss)
+}
+
+func (s *statsStore) addJitter(jitter model.Jitter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.Jitters = append(s.batch.Jitters, jitter)
+}
+
+func (s *statsStore) addHTTPTiming(timing model.HTTPTiming) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.HTTPTimings = append(s.batch.HTTPTimings, timing)
+}
+
+func (s *statsStore) addMeasurement(m model.HistogramMeasurement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.Measurements = append(s.batch.Measurements, m)
+}
+
+func (s *statsStore) addStateChange(change model.StateChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.StateChanges = append(s.batch.StateChanges, change)
+}
+
+func (s *statsStore) addLateReply(reply model.LateReply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.LateReplies = append(s.batch.LateReplies, reply)
+}
+
+func (s *statsStore) addPacketEvent(event model.PacketEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.PacketEvents = append(s.batch.PacketEvents, event)
+}
+
+func (s *statsStore) addPathChange(change model.PathChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.PathChanges = append(s.batch.PathChanges, change)
+}
+
+func (s *statsStore) addResolutionChange(change model.ResolutionChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.ResolutionChanges = append(s.batch.ResolutionChanges, change)
+}
+
+func (s *statsStore) addFamilyResult(result model.FamilyResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch.FamilyResults = append(s.batch.FamilyResults, result)
+}
+
+// flush writes the changed statistics and incidents and all pending results in a single transaction.
+// If that fails everything is kept and written with the next flush.
+func (s *statsStore) flush() error {
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = model.Batch{}
+	for uuid := range s.dirty {
+		batch.Stats = append(batch.Stats, s.stats[uuid])
+	}
+	clear(s.dirty)
+	for key := range s.dirtyFamilies {
+		batch.FamilyStats = append(batch.FamilyStats, s.families[key])
+	}
+	clear(s.dirtyFamilies)
+	for _, incident := range s.changedIncidents {
+		batch.Incidents = append(batch.Incidents, incident)
+	}
+	clear(s.changedIncidents)
+	s.mu.Unlock()
+
+	if batch.Empty() {
+		return nil
+	}
+
+	err := s.db.SaveBatch(&batch)
+	if err != nil {
+		s.requeue(batch)
+	}
+	return err
+}
+
+// requeue puts the results of a batch that failed in front of the results
+// that came in since, and marks its statistics and incidents as changed again.
+// The oldest results beyond maxPendingResults of each kind are dropped.
+func (s *statsStore) requeue(failed model.Batch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stats := range failed.Stats {
+		if _, ok := s.stats[stats.TargetUuid]; ok {
+			s.dirty[stats.TargetUuid] = true
+		}
+	}
+	for _, stats := range failed.FamilyStats {
+		key := familyKey{stats.TargetUuid, stats.Family}
+		if _, ok := s.families[key]; ok {
+			s.dirtyFamilies[key] = true
+		}
+	}
+	// An incident that changed again since is written in its latest version
+	for _, incident := range failed.Incidents {
+		key := incidentKey{incident.TargetUuid, incident.Start}
+		if _, ok := s.changedIncidents[key]; !ok {
+			s.changedIncidents[key] = incident
+		}
+	}
+
+	// Histogram counts of targets that were reset since were measured with the old settings
+	reset := make(map[string]bool, len(s.batch.HistogramResets))
+	for _, uuid := range s.batch.HistogramResets {
+		reset[uuid] = true
+	}
+	measurements := failed.Measurements[:0]
+	for _, m := range failed.Measurements {
+		if !reset[m.TargetUuid] {
+			measurements = append(measurements, m)
+		}
+	}
+
+	dropped := 0
+	s.batch = model.Batch{
+		Latencies:         capPending(append(failed.Latencies, s.batch.Latencies...), &dropped),
+		Losses:            capPending(append(failed.Losses, s.batch.Losses...), &dropped),
+		Jitters:           capPending(append(failed.Jitters, s.batch.Jitters...), &dropped),
+		HTTPTimings:       capPending(append(failed.HTTPTimings, s.batch.HTTPTimings...), &dropped),
+		Measurements:      capPending(append(measurements, s.batch.Measurements...), &dropped),
+		HistogramResets:   append(failed.HistogramResets, s.batch.HistogramResets...),
+		StateChanges:      capPending(append(failed.StateChanges, s.batch.StateChanges...), &dropped),
+		LateReplies:       capPending(append(failed.LateReplies, s.batch.LateReplies...), &dropped),
+		PacketEvents:      capPending(append(failed.PacketEvents, s.batch.PacketEvents...), &dropped),
+		PathChanges:       capPending(append(failed.PathChanges, s.batch.PathChanges...), &dropped),
+		ResolutionChanges: capPending(append(failed.ResolutionChanges, s.batch.ResolutionChanges...), &dropped),
+		FamilyResults:     capPending(append(failed.FamilyResults, s.batch.FamilyResults...), &dropped),
+	}
+	if dropped > 0 {
+		fmt.Printf("Dropped %d results that could not be written to the database\n", dropped)
+	}
+}
+
+// capPending drops the oldest results beyond maxPendingResults and counts them in dropped
+func capPending[T any](results []T, dropped *int) []T {
+	if len(results) <= maxPendingResults {
+		return results
+	}
+	*dropped += len(results) - maxPendingResults
+	return slices.Clone(results[len(results)-maxPendingResults:])
+}
@@ -0,0 +1,273 @@
+package scheduler
+
+import (
+	"errors"
+	"lagident/database"
+	"lagident/model"
+	"testing"
+)
+
+// batchDB records the batches written by a statsStore, all other methods are not implemented
+type batchDB struct {
+	database.DB
+	stats     []*model.Stats
+	families  []model.FamilyStats
+	incidents []model.Incident
+	batches   []model.Batch
+	err       error
+}
+
+func (d *batchDB) GetStats() ([]*model.Stats, error) {
+	return d.stats, nil
+}
+
+func (d *batchDB) GetFamilyStats() ([]model.FamilyStats, error) {
+	return d.families, nil
+}
+
+func (d *batchDB) GetOpenIncidents() ([]model.Incident, error) {
+	return d.incidents, nil
+}
+
+func (d *batchDB) SaveBatch(batch *model.Batch) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.batches = append(d.batches, *batch)
+	return nil
+}
+
+func TestStatsStore_Flush(t *testing.T) {
+	db := &batchDB{stats: []*model.Stats{{TargetUuid: "a", Sent: 10}}}
+	store := newStatsStore(db)
+	if err := store.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := store.get("a")
+	if stats == nil || stats.Sent != 10 {
+		t.Fatalf("get() = %+v, want the loaded stats", stats)
+	}
+	stats.Sent++
+	if store.get("a").Sent != 10 {
+		t.Error("get() should return a copy")
+	}
+
+	store.put(*stats)
+	store.addLatency(model.Latency{TargetUuid: "a", Latency: 12})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "a", Bucket: 35})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "a", Bucket: 36})
+
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.batches) != 1 {
+		t.Fatalf("flush() wrote %d batches, want 1", len(db.batches))
+	}
+	batch := db.batches[0]
+	if len(batch.Stats) != 1 || batch.Stats[0].Sent != 11 || len(batch.Latencies) != 1 || len(batch.Measurements) != 2 {
+		t.Errorf("flush() wrote %+v, want the changed stats, 1 latency and 2 measurements", batch)
+	}
+
+	// Nothing changed since the last flush
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.batches) != 1 {
+		t.Errorf("flush() wrote an empty batch")
+	}
+}
+
+func TestStatsStore_FlushError(t *testing.T) {
+	db := &batchDB{err: errors.New("database is locked")}
+	store := newStatsStore(db)
+
+	store.put(model.Stats{TargetUuid: "a"})
+	store.put(model.Stats{TargetUuid: "b"})
+	store.addLatency(model.Latency{TargetUuid: "a", Timestamp: 1})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "a", Bucket: 35})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "b", Bucket: 35})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "c", Bucket: 35})
+	if err := store.flush(); err == nil {
+		t.Fatal("flush() should return the error of the database")
+	}
+
+	// The stats and results are written with the next flush, unless the target was deleted.
+	// The results follow in order, except for the histogram counts of a target that was reset.
+	store.forget("b")
+	store.addLatency(model.Latency{TargetUuid: "a", Timestamp: 2})
+	store.reset(&model.Target{Uuid: "a"})
+	db.err = nil
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.batches) != 1 || len(db.batches[0].Stats) != 1 || db.batches[0].Stats[0].TargetUuid != "a" {
+		t.Fatalf("flush() wrote %+v, want only the stats of a", db.batches)
+	}
+	batch := db.batches[0]
+	if len(batch.Latencies) != 2 || batch.Latencies[0].Timestamp != 1 || batch.Latencies[1].Timestamp != 2 {
+		t.Errorf("flush() wrote the latencies %+v, want those of the failed batch first", batch.Latencies)
+	}
+	if len(batch.Measurements) != 1 || batch.Measurements[0].TargetUuid != "c" {
+		t.Errorf("flush() wrote %+v, want only the measurement of c", batch.Measurements)
+	}
+}
+
+func TestStatsStore_FlushErrorCap(t *testing.T) {
+	db := &batchDB{err: errors.New("database is locked")}
+	store := newStatsStore(db)
+
+	store.put(model.Stats{TargetUuid: "a"})
+	store.putIncident(model.Incident{TargetUuid: "a", Start: 100})
+	for i := 0; i <= maxPendingResults; i++ {
+		store.addLatency(model.Latency{TargetUuid: "a", Timestamp: int64(i)})
+	}
+	store.addLoss(model.Loss{TargetUuid: "a"})
+	if err := store.flush(); err == nil {
+		t.Fatal("flush() should return the error of the database")
+	}
+
+	// The oldest latencies beyond the cap are dropped, the stats and incidents are kept
+	db.err = nil
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.batches[0]
+	if len(batch.Latencies) != maxPendingResults || batch.Latencies[0].Timestamp != 1 || batch.Latencies[maxPendingResults-1].Timestamp != maxPendingResults {
+		t.Errorf("flush() wrote %d latencies, want the latest %d", len(batch.Latencies), maxPendingResults)
+	}
+	if len(batch.Losses) != 1 || len(batch.Stats) != 1 || len(batch.Incidents) != 1 {
+		t.Errorf("flush() wrote %d losses, %d stats and %d incidents, want 1 each", len(batch.Losses), len(batch.Stats), len(batch.Incidents))
+	}
+}
+
+func TestStatsStore_Forget(t *testing.T) {
+	db := &batchDB{}
+	store := newStatsStore(db)
+
+	for _, uuid := range []string{"a", "b"} {
+		store.put(model.Stats{TargetUuid: uuid})
+		store.putIncident(model.Incident{TargetUuid: uuid, Start: 100})
+		store.addLatency(model.Latency{TargetUuid: uuid})
+		store.addLoss(model.Loss{TargetUuid: uuid})
+		store.addStateChange(model.StateChange{TargetUuid: uuid})
+	}
+	store.reset(&model.Target{Uuid: "a"})
+
+	store.forget("a")
+	if store.get("a") != nil || store.openIncident("a") != nil {
+		t.Error("forget() should drop the stats and the open incident")
+	}
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.batches[0]
+	if len(batch.Stats) != 1 || len(batch.Incidents) != 1 || len(batch.Latencies) != 1 || len(batch.Losses) != 1 || len(batch.StateChanges) != 1 || len(batch.HistogramResets) != 0 {
+		t.Fatalf("flush() wrote %+v, want only the stats, incident and results of b", batch)
+	}
+	if batch.Stats[0].TargetUuid != "b" || batch.Incidents[0].TargetUuid != "b" || batch.Latencies[0].TargetUuid != "b" {
+		t.Errorf("flush() wrote %+v, want nothing of a", batch)
+	}
+}
+
+func TestStatsStore_Reset(t *testing.T) {
+	db := &batchDB{}
+	store := newStatsStore(db)
+
+	store.put(model.Stats{TargetUuid: "a", State: model.StateDown, Sent: 10, Avg15m: 20})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "a", Bucket: 35})
+	store.addMeasurement(model.HistogramMeasurement{TargetUuid: "b", Bucket: 35})
+
+	target := &model.Target{Uuid: "a", DSCP: 46}
+	store.reset(target)
+
+	stats := store.get("a")
+	if stats.State != model.StateDown || stats.Sent != 0 || stats.Avg15m != 0 || stats.ProbeSettings != target.Settings() {
+		t.Errorf("reset() left %+v, want only the state and the new settings", stats)
+	}
+
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.batches[0]
+	if len(batch.HistogramResets) != 1 || batch.HistogramResets[0] != "a" {
+		t.Errorf("flush() reset the histograms of %v, want a", batch.HistogramResets)
+	}
+	if len(batch.Measurements) != 1 || batch.Measurements[0].TargetUuid != "b" {
+		t.Errorf("flush() wrote %+v, want only the measurement of b", batch.Measurements)
+	}
+}
+
+func TestStatsStore_Incidents(t *testing.T) {
+	db := &batchDB{incidents: []model.Incident{
+		{TargetUuid: "a", Start: 100},
+		{TargetUuid: "a", Start: 200},
+	}}
+	store := newStatsStore(db)
+	if err := store.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	incident := store.openIncident("a")
+	if incident == nil || incident.Start != 200 {
+		t.Fatalf("openIncident() = %+v, want the latest one", incident)
+	}
+
+	// Every probe while the target is down updates the incident, only the last version is written
+	incident.Packets = 1
+	store.putIncident(*incident)
+	incident.Packets = 2
+	store.putIncident(*incident)
+	db.err = errors.New("database is locked")
+	if err := store.flush(); err == nil {
+		t.Fatal("flush() should return the error of the database")
+	}
+
+	// A version that changed while the batch failed is not overwritten by the failed one
+	incident.End = 300
+	store.putIncident(*incident)
+	if store.openIncident("a") != nil {
+		t.Error("openIncident() returned a closed incident")
+	}
+
+	db.err = nil
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	incidents := db.batches[0].Incidents
+	if len(incidents) != 1 || incidents[0].Packets != 2 || incidents[0].End != 300 {
+		t.Errorf("flush() wrote %+v, want the closed incident", incidents)
+	}
+}
+
+func TestStatsStore_Families(t *testing.T) {
+	db := &batchDB{families: []model.FamilyStats{{TargetUuid: "a", Family: model.FamilyIPv6, Sent: 10}}}
+	store := newStatsStore(db)
+	if err := store.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := store.family("a", model.FamilyIPv6)
+	if stats == nil || stats.Sent != 10 {
+		t.Fatalf("family() = %+v, want the loaded stats", stats)
+	}
+	if store.family("a", model.FamilyIPv4) != nil {
+		t.Error("family() returned stats for a family without any")
+	}
+
+	stats.Sent++
+	store.putFamily(*stats)
+	store.addFamilyResult(model.FamilyResult{TargetUuid: "a", Family: model.FamilyIPv6})
+	if err := store.flush(); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.batches[0]
+	if len(batch.FamilyStats) != 1 || batch.FamilyStats[0].Sent != 11 || len(batch.FamilyResults) != 1 {
+		t.Errorf("flush() wrote %+v, want the changed family stats and 1 result", batch)
+	}
+
+	store.reset(&model.Target{Uuid: "a"})
+	if store.family("a", model.FamilyIPv6) != nil {
+		t.Error("reset() kept the family stats")
+	}
+}
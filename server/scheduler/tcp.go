@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"lagident/model"
+	"net"
+	"strconv"
+	"time"
+)
+
+// TCPProber measures the time it takes to complete the TCP three-way handshake
+// with target.Address on target.Port.
+type TCPProber struct{}
+
+func (TCPProber) Probe(ctx context.Context, target *model.Target, timeout time.Duration) Result {
+	if target.Port <= 0 || target.Port > 65535 {
+		return Result{Lost: true, Err: errors.New("invalid port " + strconv.Itoa(target.Port))}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Resolve the hostname first so name resolution does not count towards the handshake
+	dialer := newDialer(target, "tcp")
+	ip, err := resolveTarget(ctx, target.Address, sourceOptions(target))
+	if err != nil {
+		return Result{Lost: true, Err: err}
+	}
+
+	address := net.JoinHostPort(ip.String(), strconv.Itoa(target.Port))
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	rtt := time.Since(start)
+	if err != nil {
+		// A refused connection is also counted as loss, the handshake never completed
+		return Result{Lost: true, Err: err, Address: ip.String()}
+	}
+	conn.Close()
+
+	return Result{Latency: milliseconds(rtt), Address: ip.String()}
+}
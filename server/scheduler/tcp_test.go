@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"lagident/model"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPProber_Probe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	target := &model.Target{
+		Address: "127.0.0.1",
+		Probe:   model.ProbeTCP,
+		Port:    listener.Addr().(*net.TCPAddr).Port,
+	}
+
+	result := TCPProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Fatalf("probe was lost: %v", result.Err)
+	}
+	if result.Latency <= 0 {
+		t.Errorf("latency should be greater than 0, got %v", result.Latency)
+	}
+	if result.Address != "127.0.0.1" {
+		t.Errorf("address = %q, want 127.0.0.1", result.Address)
+	}
+}
+
+func TestTCPProber_Probe_ClosedPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	target := &model.Target{
+		Address: "127.0.0.1",
+		Probe:   model.ProbeTCP,
+		Port:    port,
+	}
+
+	result := TCPProber{}.Probe(context.Background(), target, time.Second)
+	if !result.Lost {
+		t.Errorf("probe to closed port should be lost")
+	}
+	if result.Err == nil {
+		t.Errorf("lost probe should report an error")
+	}
+}
+
+func TestTCPProber_Probe_SourceAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	remote := make(chan net.Addr, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		remote <- conn.RemoteAddr()
+		conn.Close()
+	}()
+
+	// The whole 127.0.0.0/8 network is local on Linux
+	target := &model.Target{
+		Address:       "127.0.0.1",
+		Probe:         model.ProbeTCP,
+		Port:          listener.Addr().(*net.TCPAddr).Port,
+		SourceAddress: "127.0.0.2",
+	}
+
+	result := TCPProber{}.Probe(context.Background(), target, time.Second)
+	if result.Lost {
+		t.Skipf("cannot connect from 127.0.0.2: %v", result.Err)
+	}
+	if ip := (<-remote).(*net.TCPAddr).IP.String(); ip != "127.0.0.2" {
+		t.Errorf("connection came from %s, want 127.0.0.2", ip)
+	}
+}
+
+func TestResolveTarget_SourceFamily(t *testing.T) {
+	ip, err := resolveTarget(context.Background(), "127.0.0.1", socketOptions{source: net.ParseIP("127.0.0.2")})
+	if err != nil || ip.String() != "127.0.0.1" {
+		t.Errorf("resolveTarget = %v, %v, want 127.0.0.1", ip, err)
+	}
+
+	_, err = resolveTarget(context.Background(), "127.0.0.1", socketOptions{source: net.ParseIP("::1")})
+	if err == nil {
+		t.Error("an IPv4 target should not be reachable from an IPv6 source address")
+	}
+}
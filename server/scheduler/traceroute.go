@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"lagident/model"
+	"math/rand"
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+const (
+	// pathInterval is the time between two path discoveries of a target
+	pathInterval = 5 * time.Minute
+
+	maxHops      = 30
+	probesPerHop = 3
+	hopTimeout   = time.Second
+
+	// Stop the discovery after this many hops in a row did not answer,
+	// most likely the target drops ICMP and we would only wait for nothing.
+	maxSilentHops = 5
+)
+
+// Traceroute discovers the path to target with TTL limited ICMP echo requests, from the
+// source address, interface and network namespace of the target like its probes.
+// It returns one Hop per TTL up to the target. Requires a raw socket (root or CAP_NET_RAW).
+func Traceroute(ctx context.Context, target *model.Target) ([]model.Hop, error) {
+	opts := sourceOptions(target)
+	dst, err := resolveTarget(ctx, targetHost(target), opts)
+	if err != nil {
+		return nil, err
+	}
+	ipv6 := dst.To4() == nil
+
+	conn, err := openEchoConn(ipv6, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Ping sockets do not receive the time exceeded errors of the routers on the way
+	if !conn.privileged {
+		return nil, errors.New("traceroute needs a raw ICMP socket")
+	}
+
+	id := rand.Intn(1 << 16)
+	seq := 0
+	silent := 0
+	buf := make([]byte, 1500)
+
+	var hops []model.Hop
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if ipv6 {
+			err = conn.v6.SetHopLimit(ttl)
+		} else {
+			err = conn.v4.SetTTL(ttl)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		hop := model.Hop{Hop: ttl}
+		reached := false
+		var rttSum time.Duration
+
+		for i := 0; i < probesPerHop; i++ {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			seq++
+			start := time.Now()
+			if err := conn.sendEcho(dst, id, seq, nil); err != nil {
+				return nil, err
+			}
+			hop.Sent++
+
+			from, reply, err := readReply(conn, buf, id, seq, icmpProtocol(dst), start.Add(hopTimeout))
+			if err != nil {
+				// No answer for this probe
+				continue
+			}
+
+			rttSum += time.Since(start)
+			hop.Recv++
+			if hop.Address == "" {
+				hop.Address = from.String()
+			}
+
+			switch reply.Body.(type) {
+			case *icmp.Echo:
+				reached = true
+			case *icmp.DstUnreach:
+				// The router tells us it can't forward to the target, there is nothing behind it
+				reached = true
+			}
+		}
+
+		if hop.Recv > 0 {
+			hop.Latency = milliseconds(rttSum / time.Duration(hop.Recv))
+			silent = 0
+		} else {
+			silent++
+		}
+
+		hops = append(hops, hop)
+
+		if reached || silent >= maxSilentHops {
+			break
+		}
+	}
+
+	return hops, nil
+}
+
+// readReply waits until deadline for the ICMP message that answers the echo
+// request with id and seq. Messages for other requests are skipped.
+func readReply(conn *echoConn, buf []byte, id, seq, proto int, deadline time.Time) (net.IP, *icmp.Message, error) {
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		n, _, peer, err := conn.readPacket(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		replyID, replySeq, ok := echoReference(msg)
+		if !ok || replyID != id || replySeq != seq {
+			continue
+		}
+
+		from := peerIP(peer)
+		if from == nil {
+			return nil, nil, errors.New("unexpected peer address " + peer.String())
+		}
+		return from, msg, nil
+	}
+}
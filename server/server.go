@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"lagident/database"
+	"lagident/model"
 	"lagident/scheduler"
 	"lagident/web"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -40,6 +42,9 @@ func main() {
 	switch dbType {
 	case "mysql":
 		d, err = sql.Open("mysql", dataSource())
+		if err == nil {
+			err = database.MigrateMySQLDB(d)
+		}
 	case "sqlite":
 		d, err = sql.Open("sqlite3", sqlitePath())
 		if err == nil {
@@ -53,6 +58,26 @@ func main() {
 		log.Fatal(err)
 	}
 
+	scheme, err := histogramScheme()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	maxProbes, err := maxProbes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	flushInterval, err := flushInterval()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = database.MigrateHistograms(database.NewDB(d, dbType), scheme)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// CORS is enabled only in prod profile
 	cors := os.Getenv("PROFILE") == "prod"
 
@@ -60,7 +85,11 @@ func main() {
 		fmt.Println("Start Lagident")
 
 		db := database.NewDB(d, dbType)
-		go Run(ctx, shutdown, db, cors)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Run(ctx, shutdown, db, scheme, maxProbes, flushInterval, cors)
+		}()
 
 		select {
 		case <-ctx.Done():
@@ -72,10 +101,15 @@ func main() {
 
 				// Stop the Run() function but do not exit the program.
 				// This will trigger a reload because the outer for loop will call the Run() function again.
+				// Wait until the statistics in memory are written, the next Run() starts with them.
 				shutdown <- struct{}{}
+				<-done
 			} else {
 				// Stop Process by returning from this function (MainThreadLoop)
+				// after the statistics in memory are written
 				log.Printf("Catch signal: %v - %v", sig, sig.String())
+				shutdown <- struct{}{}
+				<-done
 				return
 			}
 		}
@@ -123,14 +157,79 @@ func sqlitePath() string {
 	return "lagident.db"
 }
 
-func Run(parent context.Context, shutdown chan struct{}, db database.DB, cors bool) {
+// histogramScheme reads the histogram buckets from the environment.
+// Defaults to the buckets of meshping: log2(latency) * 10, one hour wide.
+func histogramScheme() (model.BucketScheme, error) {
+	scheme := model.DefaultBucketScheme
+
+	if kind := os.Getenv("HISTOGRAM_BUCKETS"); kind != "" {
+		scheme.Kind = kind
+		if kind == model.BucketsLinear {
+			scheme.Resolution = 1
+		}
+	}
+
+	if resolution := os.Getenv("HISTOGRAM_RESOLUTION"); resolution != "" {
+		value, err := strconv.ParseFloat(resolution, 64)
+		if err != nil {
+			return scheme, fmt.Errorf("invalid HISTOGRAM_RESOLUTION: %w", err)
+		}
+		scheme.Resolution = value
+	}
+
+	if width := os.Getenv("HISTOGRAM_WIDTH"); width != "" {
+		value, err := strconv.ParseInt(width, 10, 64)
+		if err != nil {
+			return scheme, fmt.Errorf("invalid HISTOGRAM_WIDTH: %w", err)
+		}
+		scheme.Width = value
+	}
+
+	return scheme, scheme.Validate()
+}
+
+// maxProbes reads the number of probes that may run at the same time from the environment
+func maxProbes() (int, error) {
+	value := os.Getenv("MAX_PROBES")
+	if value == "" {
+		return scheduler.DefaultMaxProbes, nil
+	}
+
+	max, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid MAX_PROBES: %w", err)
+	}
+	if max < 1 {
+		return 0, fmt.Errorf("MAX_PROBES must be at least 1")
+	}
+	return max, nil
+}
+
+// flushInterval reads the time between two writes of the statistics from the environment
+func flushInterval() (time.Duration, error) {
+	value := os.Getenv("FLUSH_INTERVAL")
+	if value == "" {
+		return scheduler.DefaultFlushInterval, nil
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid FLUSH_INTERVAL: %w", err)
+	}
+	if seconds < 1 {
+		return 0, fmt.Errorf("FLUSH_INTERVAL must be at least 1 second")
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func Run(parent context.Context, shutdown chan struct{}, db database.DB, scheme model.BucketScheme, maxProbes int, flushInterval time.Duration, cors bool) {
 	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	webserver := web.NewWebserver(db, cors)
 	webserver.StartWebserver(ctx)
 
-	scheduler := scheduler.NewScheduler(db)
+	scheduler := scheduler.NewScheduler(db, scheme, maxProbes, flushInterval)
 	scheduler.StartScheduler(ctx)
 
 	housekeeping := database.NewHousekeeping(db)
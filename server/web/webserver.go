@@ -2,10 +2,17 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"lagident/database"
+	"lagident/scheduler"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,12 +32,31 @@ type Webserver struct {
 type StatisticResponse struct {
 	Target     model.Target
 	Statistics model.Stats
+	Windows    Windows
+	// Families holds the IPv4 and IPv6 statistics of dual-stack targets
+	Families []model.FamilyStats
+}
+
+// Windows summarizes the latencies of a target over the same windows as the moving averages
+type Windows struct {
+	Window15m model.Window `json:"15m"`
+	Window6h  model.Window `json:"6h"`
+	Window24h model.Window `json:"24h"`
 }
 
 type TimeseriesResponse struct {
-	Target    model.Target
-	Latencies []model.Latency
-	Losses    []model.Loss
+	Target       model.Target
+	Latencies    []model.Latency
+	Losses       []model.Loss
+	Jitters      []model.Jitter
+	HTTPTimings  []model.HTTPTiming
+	LateReplies  []model.LateReply
+	PacketEvents []model.PacketEvent
+	PathMTUs     []model.PathMTU
+	// FamilyResults holds the IPv4 and IPv6 series of dual-stack targets
+	FamilyResults []model.FamilyResult
+	// Annotations mark events like path changes that help to explain the latency
+	Annotations []model.Annotation
 }
 
 func NewWebserver(db database.DB, cors bool) *Webserver {
@@ -65,6 +91,10 @@ func NewWebserver(db database.DB, cors bool) *Webserver {
 
 		api.GET("timeseries/:uuid", webserver.GetTimeSeries)
 		api.GET("histograms/:uuid", webserver.GetHistogram)
+		api.GET("paths/:uuid", webserver.GetPaths)
+		api.GET("percentiles/:uuid", webserver.GetPercentiles)
+		api.GET("incidents", webserver.GetIncidents)
+		api.GET("states/:uuid", webserver.GetStates)
 	}
 
 	webserver.server = &http.Server{
@@ -143,6 +173,11 @@ func (w *Webserver) AddTarget(c *gin.Context) {
 		return
 	}
 
+	if err := validateTarget(&target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	err := w.db.AddTarget(target)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -152,6 +187,110 @@ func (w *Webserver) AddTarget(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Target added successfully"})
 }
 
+// validateTarget checks the probe settings of a new target and fills in defaults
+func validateTarget(target *model.Target) error {
+	if target.Interval == 0 {
+		target.Interval = model.DefaultInterval
+	}
+	if target.Timeout == 0 {
+		target.Timeout = min(model.DefaultTimeout, target.Interval*1000)
+	}
+	if target.Count == 0 {
+		target.Count = model.DefaultCount
+	}
+	if target.Spacing == 0 {
+		target.Spacing = model.DefaultSpacing
+	}
+	if target.FailThreshold == 0 {
+		target.FailThreshold = model.DefaultFailThreshold
+	}
+	if target.SuccessThreshold == 0 {
+		target.SuccessThreshold = model.DefaultSuccessThreshold
+	}
+	if target.SlowThreshold == 0 {
+		target.SlowThreshold = model.DefaultSlowThreshold
+	}
+	if target.FastThreshold == 0 {
+		target.FastThreshold = model.DefaultFastThreshold
+	}
+	if target.Interval < 1 {
+		return errors.New("interval must be at least 1 second")
+	}
+	if target.Timeout < 1 || target.Timeout > target.Interval*1000 {
+		return errors.New("timeout must be between 1 ms and the interval")
+	}
+	if target.Count < 1 || target.Count > 100 {
+		return errors.New("count must be between 1 and 100")
+	}
+	if target.Spacing < 10 {
+		return errors.New("spacing must be at least 10 ms")
+	}
+	if (target.Count-1)*target.Spacing >= target.Interval*1000 {
+		return errors.New("a burst must be shorter than the interval")
+	}
+	if target.FailThreshold < 1 || target.SuccessThreshold < 1 {
+		return errors.New("fail_threshold and success_threshold must be at least 1")
+	}
+	if target.DegradedLatency < 0 {
+		return errors.New("degraded_latency must not be negative")
+	}
+	if target.SlowThreshold < 1 || target.FastThreshold < 1 {
+		return errors.New("slow_threshold and fast_threshold must be at least 1")
+	}
+	if target.PayloadSize < 0 || target.PayloadSize > model.MaxPayloadSize {
+		return fmt.Errorf("payload_size must be between 0 and %d bytes", model.MaxPayloadSize)
+	}
+	if target.DSCP < 0 || target.DSCP > model.MaxDSCP {
+		return fmt.Errorf("dscp must be between 0 and %d", model.MaxDSCP)
+	}
+	if target.SourceAddress != "" && net.ParseIP(target.SourceAddress) == nil {
+		return errors.New("source_address must be an IP address")
+	}
+	if strings.Contains(target.Netns, "/") || target.Netns == "." || target.Netns == ".." {
+		return errors.New("netns must be the name of a network namespace in /var/run/netns")
+	}
+	// Interface names are limited to 15 characters by Linux
+	if len(target.Interface) > 15 || strings.ContainsAny(target.Interface, "/ ") {
+		return errors.New("interface must be the name of a network interface")
+	}
+	if target.DualStack && target.SourceAddress != "" {
+		return errors.New("dual_stack targets can not be bound to a source_address")
+	}
+	if target.DualStack && target.Probe != "" && target.Probe != model.ProbeICMP && target.Probe != model.ProbeTCP {
+		return errors.New("dual_stack is only supported for icmp and tcp targets")
+	}
+
+	switch target.Probe {
+	case "":
+		target.Probe = model.ProbeICMP
+	case model.ProbeICMP:
+	case model.ProbeTCP:
+		if target.Port <= 0 || target.Port > 65535 {
+			return errors.New("tcp targets require a port between 1 and 65535")
+		}
+	case model.ProbeHTTP:
+		u, err := url.Parse(target.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("http targets require an http:// or https:// URL as address")
+		}
+	case model.ProbeDNS:
+		if target.QueryName == "" {
+			return errors.New("dns targets require a query_name")
+		}
+		if target.RecordType == "" {
+			target.RecordType = "A"
+		}
+		if !scheduler.IsRecordType(target.RecordType) {
+			return fmt.Errorf("unsupported record type %q", target.RecordType)
+		}
+		target.RecordType = strings.ToUpper(target.RecordType)
+	default:
+		return fmt.Errorf("unknown probe kind %q", target.Probe)
+	}
+
+	return nil
+}
+
 func (w *Webserver) GetTargetByUuid(c *gin.Context) {
 	uuid := c.Param("uuid")
 	target, err := w.db.GetTargetByUuid(uuid)
@@ -180,6 +319,12 @@ func (w *Webserver) DeleteTarget(c *gin.Context) {
 		return
 	}
 
+	err = w.db.DeleteFamilyStats(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Target deleted successfully"})
 }
 
@@ -196,8 +341,25 @@ func (w *Webserver) GetStatistics(c *gin.Context) {
 		return
 	}
 
+	now := time.Now()
+	windows := make(map[time.Duration]map[string]model.Window)
+	for _, d := range []time.Duration{15 * time.Minute, 6 * time.Hour, 24 * time.Hour} {
+		windows[d], err = w.db.GetLatencyWindows(now.Add(-d))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	familyStats, err := w.db.GetFamilyStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	targetMap := make(map[string]model.Target)
 	statsMap := make(map[string]model.Stats)
+	familyMap := make(map[string][]model.FamilyStats)
 
 	for _, target := range targsts {
 		targetMap[target.Uuid] = *target
@@ -207,13 +369,30 @@ func (w *Webserver) GetStatistics(c *gin.Context) {
 		statsMap[stat.TargetUuid] = *stat
 	}
 
+	for _, family := range familyStats {
+		familyMap[family.TargetUuid] = append(familyMap[family.TargetUuid], family)
+	}
+
 	result := make([]StatisticResponse, 0, len(targsts))
 	for _, target := range targsts {
 		stat, ok := statsMap[target.Uuid]
 		if !ok {
 			stat = model.Stats{TargetUuid: target.Uuid}
 		}
-		result = append(result, StatisticResponse{Target: *target, Statistics: stat})
+		families, ok := familyMap[target.Uuid]
+		if !ok {
+			families = make([]model.FamilyStats, 0)
+		}
+		result = append(result, StatisticResponse{
+			Target:     *target,
+			Statistics: stat,
+			Windows: Windows{
+				Window15m: windows[15*time.Minute][target.Uuid],
+				Window6h:  windows[6*time.Hour][target.Uuid],
+				Window24h: windows[24*time.Hour][target.Uuid],
+			},
+			Families: families,
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{"targets": result})
@@ -257,10 +436,91 @@ func (w *Webserver) GetTimeSeries(c *gin.Context) {
 		return
 	}
 
+	jitters, err := w.db.GetJitterByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	httpTimings, err := w.db.GetHTTPTimingByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	lateReplies, err := w.db.GetLateRepliesByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	packetEvents, err := w.db.GetPacketEventsByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pathChanges, err := w.db.GetPathChangesByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pathMTUs, err := w.db.GetPathMTUsByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	mtuChanges, err := w.db.GetMTUChangesByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	familyResults, err := w.db.GetFamilyResultsByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resolutionChanges, err := w.db.GetResolutionChangesByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	latencies := latenciesWith(latency, target.Settings())
+	familyResults = familyResultsWith(familyResults, target.Settings())
+
+	annotations := make([]model.Annotation, 0, len(pathChanges)+len(mtuChanges)+len(resolutionChanges))
+	for _, change := range pathChanges {
+		annotations = append(annotations, change.Annotation())
+	}
+	for _, change := range mtuChanges {
+		annotations = append(annotations, change.Annotation())
+	}
+	for _, change := range resolutionChanges {
+		annotations = append(annotations, change.Annotation())
+		if shift, ok := change.LatencyShift(familyLatencies(latencies, familyResults, change.Family)); ok {
+			annotations = append(annotations, shift)
+		}
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].Timestamp < annotations[j].Timestamp
+	})
+
 	response := TimeseriesResponse{
-		Target:    *target,
-		Latencies: latency,
-		Losses:    loss,
+		Target:        *target,
+		Latencies:     latencies,
+		Losses:        lossesWith(loss, target.Settings()),
+		Jitters:       jittersWith(jitters, target.Settings()),
+		HTTPTimings:   httpTimings,
+		LateReplies:   lateReplies,
+		PacketEvents:  packetEvents,
+		PathMTUs:      pathMTUs,
+		FamilyResults: familyResults,
+		Annotations:   annotations,
 	}
 
 	// Make sure to return an empty array to keep the API consistent
@@ -272,6 +532,254 @@ func (w *Webserver) GetTimeSeries(c *gin.Context) {
 		response.Losses = make([]model.Loss, 0)
 	}
 
+	if response.Jitters == nil {
+		response.Jitters = make([]model.Jitter, 0)
+	}
+
+	if response.HTTPTimings == nil {
+		response.HTTPTimings = make([]model.HTTPTiming, 0)
+	}
+
+	if response.LateReplies == nil {
+		response.LateReplies = make([]model.LateReply, 0)
+	}
+
+	if response.PacketEvents == nil {
+		response.PacketEvents = make([]model.PacketEvent, 0)
+	}
+
+	if response.PathMTUs == nil {
+		response.PathMTUs = make([]model.PathMTU, 0)
+	}
+
+	if response.FamilyResults == nil {
+		response.FamilyResults = make([]model.FamilyResult, 0)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"response": response})
 
 }
+
+func (w *Webserver) GetPaths(c *gin.Context) {
+	uuid := c.Param("uuid")
+
+	target, err := w.db.GetTargetByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+		return
+	}
+
+	hops, err := w.db.GetHopsByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Hops are ordered by timestamp, so all hops of one path are next to each other
+	paths := make([]model.Path, 0)
+	for _, hop := range hops {
+		if len(paths) == 0 || paths[len(paths)-1].Timestamp != hop.Timestamp {
+			paths = append(paths, model.Path{Timestamp: hop.Timestamp})
+		}
+		last := &paths[len(paths)-1]
+		last.Hops = append(last.Hops, hop)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"paths": paths})
+}
+
+// GetStates returns the state history of a target
+func (w *Webserver) GetStates(c *gin.Context) {
+	uuid := c.Param("uuid")
+
+	target, err := w.db.GetTargetByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+		return
+	}
+
+	changes, err := w.db.GetStateChangesByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if changes == nil {
+		changes = []model.StateChange{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"states": changes})
+}
+
+// GetPercentiles summarizes the latencies of a target between the unix timestamps
+// in the from and to query parameters. Defaults to the last 24 hours.
+func (w *Webserver) GetPercentiles(c *gin.Context) {
+	uuid := c.Param("uuid")
+
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	target, err := w.db.GetTargetByUuid(uuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+		return
+	}
+
+	latencies, err := w.db.GetLatencyByUuidBetween(uuid, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	latencies = latenciesWith(latencies, target.Settings())
+
+	values := make([]float64, len(latencies))
+	for i, l := range latencies {
+		values[i] = l.Latency
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   from.Unix(),
+		"to":     to.Unix(),
+		"window": model.NewWindow(values),
+	})
+}
+
+// familyLatencies returns the latencies of an address family of a dual-stack target,
+// or latencies if family is empty
+func familyLatencies(latencies []model.Latency, results []model.FamilyResult, family string) []model.Latency {
+	if family == "" {
+		return latencies
+	}
+
+	var filtered []model.Latency
+	for _, r := range results {
+		if r.Family == family && !r.Lost {
+			filtered = append(filtered, model.Latency{TargetUuid: r.TargetUuid, Timestamp: r.Timestamp, Latency: r.Latency, Address: r.Address})
+		}
+	}
+	return filtered
+}
+
+// latenciesWith returns the latencies that were measured with settings.
+// Results of different payload sizes or DSCP values are not comparable.
+func latenciesWith(latencies []model.Latency, settings model.ProbeSettings) []model.Latency {
+	var filtered []model.Latency
+	for _, l := range latencies {
+		if l.ProbeSettings == settings {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered
+}
+
+// lossesWith returns the losses that were measured with settings
+func lossesWith(losses []model.Loss, settings model.ProbeSettings) []model.Loss {
+	var filtered []model.Loss
+	for _, l := range losses {
+		if l.ProbeSettings == settings {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered
+}
+
+// jittersWith returns the jitters that were measured with settings
+func jittersWith(jitters []model.Jitter, settings model.ProbeSettings) []model.Jitter {
+	var filtered []model.Jitter
+	for _, j := range jitters {
+		if j.ProbeSettings == settings {
+			filtered = append(filtered, j)
+		}
+	}
+	return filtered
+}
+
+// familyResultsWith returns the results of dual-stack targets that were measured with settings
+func familyResultsWith(results []model.FamilyResult, settings model.ProbeSettings) []model.FamilyResult {
+	var filtered []model.FamilyResult
+	for _, r := range results {
+		if r.ProbeSettings == settings {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// timeRange reads the from and to query parameters as unix timestamps.
+// Defaults to the last 24 hours. Responds with an error if they are invalid.
+func timeRange(c *gin.Context) (from, to time.Time, ok bool) {
+	to = time.Now()
+	if c.Query("to") != "" {
+		ts, err := strconv.ParseInt(c.Query("to"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a unix timestamp"})
+			return from, to, false
+		}
+		to = time.Unix(ts, 0)
+	}
+
+	from = to.Add(-24 * time.Hour)
+	if c.Query("from") != "" {
+		ts, err := strconv.ParseInt(c.Query("from"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a unix timestamp"})
+			return from, to, false
+		}
+		from = time.Unix(ts, 0)
+	}
+
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return from, to, false
+	}
+
+	return from, to, true
+}
+
+// GetIncidents returns the outages in a time range, optionally of a single target
+func (w *Webserver) GetIncidents(c *gin.Context) {
+	uuid := c.Query("target")
+
+	from, to, ok := timeRange(c)
+	if !ok {
+		return
+	}
+
+	if uuid != "" {
+		target, err := w.db.GetTargetByUuid(uuid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if target == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+			return
+		}
+	}
+
+	incidents, err := w.db.GetIncidents(uuid, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if incidents == nil {
+		incidents = []model.Incident{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"incidents": incidents})
+}
@@ -3,16 +3,102 @@ import { Target } from "./targets/targets.interfaces";
 export interface TimeseriesResponse {
     Target: Target,
     Latencies: Latency[],
-    Losses: Loss[]
+    Losses: Loss[],
+    Jitters: Jitter[],
+    HTTPTimings: HTTPTiming[],
+    LateReplies: LateReply[],
+    PacketEvents: PacketEvent[],
+    PathMTUs: PathMTU[],
+    FamilyResults: FamilyResult[],
+    Annotations: Annotation[]
 }
 
 export interface Latency {
     target_uuid: string,
     timestamp: number, //unix timestamp
     latency: number // latecny value in ms
+    rcode: string // DNS response code, empty for other probes
+    ttl: number // TTL of the last echo reply, 0 for other probes
+    payload_size: number // settings the latency was measured with
+    dscp: number
+    dont_fragment: boolean
+    address: string // IP address the probe was sent to
 }
 
 export interface Loss {
     target_uuid: string,
     timestamp: number, //unix timestamp
-}
\ No newline at end of file
+    rcode: string // DNS response code, empty for other probes
+    reason: string // timeout, resolve, send, unreachable, time_exceeded, refused, status, rcode or error
+    icmp_type: number // ICMP error instead of a reply, 0 if there was none
+    icmp_code: number
+    payload_size: number // settings the loss was measured with
+    dscp: number
+    dont_fragment: boolean
+    address: string // IP address the probe was sent to, empty if it could not be resolved
+}
+
+export interface Jitter {
+    target_uuid: string,
+    timestamp: number, //unix timestamp
+    jitter: number // RFC 3550 interarrival jitter in ms
+    payload_size: number // settings the jitter was measured with
+    dscp: number
+    dont_fragment: boolean
+}
+
+export interface LateReply {
+    target_uuid: string,
+    timestamp: number, //unix timestamp
+    seq: number, // position of the request in its burst
+    latency: number // real round trip time in ms
+}
+
+export interface PacketEvent {
+    target_uuid: string,
+    timestamp: number, //unix timestamp
+    kind: string, // duplicate, reordered or corrupted
+    count: number // number of replies of the probe
+}
+
+export interface PathMTU {
+    target_uuid: string,
+    timestamp: number, //unix timestamp
+    mtu: number // largest IP packet in bytes that passed with don't fragment
+}
+
+export interface FamilyResult {
+    target_uuid: string,
+    timestamp: number, //unix timestamp
+    family: string, // ipv4 or ipv6
+    address: string, // empty if the host name has no address of the family
+    latency: number, // in ms, 0 if the probe was lost
+    lost: boolean,
+    reason: string,
+    payload_size: number, // settings the result was measured with
+    dscp: number,
+    dont_fragment: boolean
+}
+
+export interface Annotation {
+    timestamp: number, //unix timestamp
+    kind: string, // path_changed, mtu_changed, address_changed or latency_shifted
+    text: string
+}
+
+export interface HTTPTiming {
+    target_uuid: string,
+    timestamp: number, //unix timestamp
+    dns: number, // all phases in ms
+    connect: number,
+    tls: number,
+    ttfb: number
+}
+
+export interface Incident {
+    target_uuid: string,
+    start: number, //unix timestamp
+    end: number, //unix timestamp, 0 while the outage is ongoing
+    duration: number, // in seconds
+    packets: number // lost packets during the outage
+}
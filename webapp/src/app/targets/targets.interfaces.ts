@@ -2,11 +2,33 @@ export interface Target {
     uuid: string,
     name: string,
     address: string
+    probe?: string
+    port?: number
+    query_name?: string
+    record_type?: string
+    traceroute?: boolean
+    mtu_discovery?: boolean
+    interval?: number // seconds
+    timeout?: number // milliseconds
+    count?: number
+    spacing?: number // milliseconds
+    fail_threshold?: number
+    success_threshold?: number
+    degraded_latency?: number // milliseconds, 0 disables it
+    slow_threshold?: number
+    fast_threshold?: number
+    payload_size?: number // bytes, 0 for the default of 56
+    dscp?: number
+    dont_fragment?: boolean
+    source_address?: string // local IP, empty for the default route
+    interface?: string // network interface, e.g. wwan0
+    netns?: string // name of a network namespace in /var/run/netns
+    dual_stack?: boolean // probe the IPv4 and the IPv6 address of a host name
 }
 
 export interface Statistics {
     target_uuid: string
-    state: string
+    state: string // up, down, degraded or flapping
     sent: number
     recv: number
     last: number
@@ -20,11 +42,68 @@ export interface Statistics {
     avg15m: number
     avg6h: number
     avg24h: number
+    jitter: number
+    burst_min: number
+    burst_avg: number
+    burst_max: number
+    burst_loss: number
+    loss15m: number
+    loss6h: number
+    loss24h: number
+    rfactor15m: number
+    rfactor6h: number
+    rfactor24h: number
+    mos15m: number
+    mos6h: number
+    mos24h: number
+    late: number
+    duplicates: number
+    reordered: number
+    corrupted: number
+    ttl: number // TTL of the last echo reply, 0 if unknown
+    hops: number // inferred from the TTL
+    address: string // IP address of the last probe
     timestamp: number
+    payload_size: number // settings the statistics were measured with
+    dscp: number
+    dont_fragment: boolean
 }
 
 
+export interface FamilyStatistics {
+    target_uuid: string
+    family: string // ipv4 or ipv6
+    address: string
+    sent: number
+    recv: number
+    last: number
+    min: number
+    max: number
+    avg15m: number
+    avg6h: number
+    avg24h: number
+    loss15m: number
+    loss6h: number
+    loss24h: number
+    timestamp: number
+}
+
+export interface Window {
+    samples: number
+    min: number
+    max: number
+    p50: number
+    p95: number
+    p99: number
+}
+
 export interface TargetWithStatistics {
     Target: Target
     Statistics?: Statistics
+    Windows?: {
+        '15m': Window
+        '6h': Window
+        '24h': Window
+    }
+    Families?: FamilyStatistics[]
 }
\ No newline at end of file